@@ -18,7 +18,7 @@ func main() {
 	}
 
 	// setup s3
-	err = yps.OpenS3(config.UploadS3Bucket, config.UploadS3KeyPrefix, config.UploadS3URLPrefix)
+	err = yps.OpenS3(config.UploadS3Bucket, config.UploadS3KeyPrefix, config.UploadS3URLPrefix, config.PrivateS3KeyPrefix)
 	if err != nil {
 		log.Fatal("OpenS3 failed:", err)
 	}
@@ -29,6 +29,17 @@ func main() {
 		log.Fatal("SetupAuth failed:", err)
 	}
 
+	// setup import options
+	if config.AuthorSeparators != "" {
+		yps.SetAuthorSeparators(strings.Fields(config.AuthorSeparators))
+	}
+	if config.TranslationTargets != "" {
+		err = yps.SetTranslationTargets(strings.Split(config.TranslationTargets, ","))
+		if err != nil {
+			log.Fatal("SetTranslationTargets failed:", err)
+		}
+	}
+
 	// upgrading db
 	m, err := migrate.New("file://"+config.DatabaseMigrationsPath, config.DatabaseUrl)
 	if err != nil {
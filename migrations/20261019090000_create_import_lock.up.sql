@@ -0,0 +1,8 @@
+-- who currently holds the import advisory lock. only ever has one row, and
+-- is only trusted while the advisory lock itself is actually held.
+CREATE TABLE import_lock (
+  id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+  holder TEXT NOT NULL,
+  action TEXT NOT NULL,
+  acquired_at TIMESTAMP NOT NULL DEFAULT (now() at time zone 'utc')
+);
@@ -0,0 +1,2 @@
+ALTER TABLE temp_insert_entries DROP COLUMN IF EXISTS date_precision;
+ALTER TABLE entries DROP COLUMN IF EXISTS date_precision;
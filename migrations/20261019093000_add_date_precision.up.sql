@@ -0,0 +1,10 @@
+ALTER TABLE entries ADD COLUMN date_precision text NOT NULL DEFAULT 'unknown';
+ALTER TABLE temp_insert_entries ADD COLUMN date_precision text NOT NULL DEFAULT 'unknown';
+
+-- best guess for existing rows, which get the real value on the next import.
+-- dates used to default to the 1st of January / the 1st of the month.
+UPDATE entries SET date_precision = CASE
+  WHEN start_date IS NULL OR start_date < '1800-01-01' THEN 'unknown'
+  WHEN date_part('month', start_date) = 1 AND date_part('day', start_date) = 1 THEN 'year'
+  WHEN date_part('day', start_date) = 1 THEN 'month'
+  ELSE 'day' END;
@@ -0,0 +1,19 @@
+DROP INDEX alltextsearch_idx;
+ALTER TABLE entries
+  DROP COLUMN IF EXISTS alltextsearch_index_col;
+
+-- existing values are split on semicolons, the next import splits them properly
+ALTER TABLE entries
+  ALTER COLUMN authors TYPE text[] USING array_remove(regexp_split_to_array(trim(authors), '\s*;\s*'), '');
+ALTER TABLE temp_insert_entries
+  ALTER COLUMN authors TYPE text[] USING array_remove(regexp_split_to_array(trim(authors), '\s*;\s*'), '');
+
+ALTER TABLE entries ADD COLUMN authors_et_al boolean NOT NULL DEFAULT false;
+ALTER TABLE temp_insert_entries ADD COLUMN authors_et_al boolean NOT NULL DEFAULT false;
+
+CREATE INDEX authors_idx ON entries USING GIN (authors);
+
+ALTER TABLE entries
+  ADD COLUMN alltextsearch_index_col tsvector
+    GENERATED ALWAYS AS (to_tsvector('english', coalesce(title, '') || ' ' || coalesce(f_kwarr2text(authors), '') || ' ' || coalesce(f_kwarr2text(keywords), '') || ' ' || coalesce(f_kwarr2text(regions), '') || ' ' || coalesce(abstract, ''))) STORED;
+CREATE INDEX alltextsearch_idx ON entries USING GIN (alltextsearch_index_col);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS orgs_idx;
+DROP TABLE IF EXISTS organisations;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS organisations (
+  "id" serial PRIMARY KEY,
+  "name" text NOT NULL UNIQUE,
+  "aliases" text[] NOT NULL DEFAULT '{}',
+  "org_type" text NOT NULL DEFAULT '',
+  "country" text NOT NULL DEFAULT '',
+  "website" text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX orgs_idx ON entries USING GIN (orgs);
+
+-- every publisher already in the db starts out as its own organisation,
+-- variants get merged by making them aliases
+INSERT INTO organisations (name, org_type)
+SELECT org_name, coalesce((
+    SELECT org_type FROM entries e
+    WHERE org_name = ANY(e.orgs) AND e.org_type <> ''
+    GROUP BY org_type
+    ORDER BY count(*) desc, org_type asc
+    LIMIT 1
+  ), '')
+FROM (
+  SELECT DISTINCT trim(unnest(orgs)) AS org_name FROM entries
+) names
+WHERE org_name <> ''
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS vocabulary_rules (
+  "id" serial PRIMARY KEY,
+  "field" text NOT NULL,
+  "match_type" text NOT NULL DEFAULT 'exact',
+  "raw_value" text NOT NULL,
+  "canonical_value" text NOT NULL,
+  "priority" integer NOT NULL DEFAULT 100,
+  UNIQUE ("field", "match_type", "raw_value")
+);
+
+-- youth-led classification, in the same order the importer used to check it.
+-- co-authored comes first so 'No, co-authored with adults' and 'Yes, co-authored
+-- with adults' are both Co-authored
+INSERT INTO vocabulary_rules (field, match_type, raw_value, canonical_value, priority) VALUES
+  ('youth_led', 'contains', 'co-authored', 'Co-authored', 10),
+  ('youth_led', 'contains', 'co authored', 'Co-authored', 10),
+  ('youth_led', 'prefix', 'yes', 'Yes', 20),
+  ('youth_led', 'prefix', 'youth-led', 'Yes', 20),
+  ('youth_led', 'prefix', 'no', 'No', 20),
+  ('youth_led', 'prefix', 'n/a', 'N/A', 20)
+ON CONFLICT DO NOTHING;
+
+-- values that only differ by case or spacing get mapped to their most common spelling
+WITH raw_values AS (
+  SELECT 'entry_type' AS field, entry_type AS value FROM entries
+  UNION ALL
+  SELECT 'org_type', org_type FROM entries
+  UNION ALL
+  SELECT 'keyword', unnest(keywords) FROM entries
+), counted AS (
+  SELECT field, value, lower(regexp_replace(trim(value), '\s+', ' ', 'g')) AS normalised, count(*) AS uses
+  FROM raw_values
+  WHERE trim(coalesce(value, '')) <> ''
+  GROUP BY field, value
+), ranked AS (
+  SELECT field, value, normalised,
+    row_number() OVER (PARTITION BY field, normalised ORDER BY uses desc, value asc) AS rank,
+    count(*) OVER (PARTITION BY field, normalised) AS variants
+  FROM counted
+)
+INSERT INTO vocabulary_rules (field, match_type, raw_value, canonical_value)
+SELECT field, 'exact', normalised, value
+FROM ranked
+WHERE rank = 1 AND variants > 1
+ON CONFLICT DO NOTHING;
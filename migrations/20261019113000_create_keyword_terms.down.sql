@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS keywords_idx;
+DROP TABLE IF EXISTS keyword_terms;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS keyword_terms (
+  "id" serial PRIMARY KEY,
+  "name" text NOT NULL UNIQUE,
+  "description" text NOT NULL DEFAULT '',
+  "synonyms" text[] NOT NULL DEFAULT '{}',
+  "broader_id" integer REFERENCES keyword_terms (id) ON DELETE SET NULL
+);
+
+CREATE INDEX keywords_idx ON entries USING GIN (keywords);
+
+-- every keyword already in the db starts out as a top-level term
+INSERT INTO keyword_terms (name)
+SELECT DISTINCT keyword
+FROM entries cross join lateral unnest(entries.keywords) keyword
+WHERE trim(keyword) <> ''
+ON CONFLICT DO NOTHING;
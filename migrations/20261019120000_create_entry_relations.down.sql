@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS entry_relations;
+DROP TABLE IF EXISTS relation_types;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS relation_types (
+  "name" text PRIMARY KEY,
+  "label" text NOT NULL,
+  "inverse_label" text NOT NULL,
+  "symmetric" boolean NOT NULL DEFAULT false
+);
+
+INSERT INTO relation_types (name, label, inverse_label, symmetric) VALUES
+  ('supersedes', 'Supersedes', 'Superseded by', false),
+  ('summary-of', 'Summary of', 'Summarised in', false),
+  ('response-to', 'Response to', 'Responded to by', false),
+  ('series', 'Same series as', 'Same series as', true)
+ON CONFLICT DO NOTHING;
+
+-- plain related links stay in entries.related, these are the typed ones
+CREATE TABLE IF NOT EXISTS entry_relations (
+  "entry_id" text NOT NULL REFERENCES entries (id) ON DELETE CASCADE,
+  "related_id" text NOT NULL,
+  "relation_type" text NOT NULL REFERENCES relation_types (name) ON UPDATE CASCADE,
+  PRIMARY KEY ("entry_id", "related_id", "relation_type")
+);
+
+CREATE INDEX entry_relations_related_idx ON entry_relations (related_id);
@@ -0,0 +1,2 @@
+-- set when an entry's created or edited through the API, cleared when an import writes it
+ALTER TABLE entries ADD COLUMN manually_edited_at timestamptz;
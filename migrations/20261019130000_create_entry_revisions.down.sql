@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS entries_revisions_trigger ON entries;
+DROP FUNCTION IF EXISTS f_record_entry_revision();
+DROP TABLE IF EXISTS entry_revisions;
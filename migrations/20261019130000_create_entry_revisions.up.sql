@@ -0,0 +1,55 @@
+CREATE TABLE "entry_revisions" (
+  "id" serial PRIMARY KEY,
+  "entry_id" text COLLATE numeric NOT NULL,
+  "changed_at" timestamptz NOT NULL DEFAULT (now()),
+  "action" text NOT NULL,
+  "source" text NOT NULL,
+  "actor" text NOT NULL,
+  "old_values" jsonb,
+  "new_values" jsonb
+);
+
+CREATE INDEX entry_revisions_entry_idx ON entry_revisions (entry_id, id);
+
+-- every change to an entry is recorded here. whoever makes the change sets
+-- yps.change_source and yps.change_actor for the transaction beforehand.
+CREATE OR REPLACE FUNCTION f_record_entry_revision()
+  RETURNS trigger LANGUAGE plpgsql AS $$
+DECLARE
+  entry_id text;
+  old_values jsonb;
+  new_values jsonb;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    entry_id := OLD.id;
+  ELSE
+    entry_id := NEW.id;
+  END IF;
+
+  -- search columns are generated, and marking an entry as edited isn't a change itself
+  IF TG_OP <> 'INSERT' THEN
+    old_values := to_jsonb(OLD) - 'titlesearch_index_col' - 'abstractsearch_index_col' - 'alltextsearch_index_col' - 'manually_edited_at';
+  END IF;
+  IF TG_OP <> 'DELETE' THEN
+    new_values := to_jsonb(NEW) - 'titlesearch_index_col' - 'abstractsearch_index_col' - 'alltextsearch_index_col' - 'manually_edited_at';
+  END IF;
+  IF old_values = new_values THEN
+    RETURN NULL;
+  END IF;
+
+  INSERT INTO entry_revisions (entry_id, action, source, actor, old_values, new_values)
+  VALUES (
+    entry_id,
+    lower(TG_OP),
+    coalesce(nullif(current_setting('yps.change_source', true), ''), 'unknown'),
+    coalesce(nullif(current_setting('yps.change_actor', true), ''), 'unknown'),
+    old_values,
+    new_values
+  );
+  RETURN NULL;
+END
+$$;
+
+CREATE TRIGGER entries_revisions_trigger
+  AFTER INSERT OR UPDATE OR DELETE ON entries
+  FOR EACH ROW EXECUTE FUNCTION f_record_entry_revision();
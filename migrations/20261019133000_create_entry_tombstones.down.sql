@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS entry_id_aliases;
+DROP TABLE IF EXISTS entry_tombstones;
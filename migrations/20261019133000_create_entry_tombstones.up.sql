@@ -0,0 +1,15 @@
+-- entries that have been deleted, so links to them can say why or point somewhere else
+CREATE TABLE "entry_tombstones" (
+  "id" text COLLATE numeric PRIMARY KEY,
+  "deleted_at" timestamptz NOT NULL DEFAULT (now()),
+  "reason" text NOT NULL DEFAULT '',
+  "replaced_by" text COLLATE numeric
+);
+
+-- old IDs that lead to an entry, declared in the import sheet
+CREATE TABLE "entry_id_aliases" (
+  "alias" text COLLATE numeric PRIMARY KEY,
+  "entry_id" text COLLATE numeric NOT NULL REFERENCES entries (id) ON DELETE CASCADE
+);
+
+CREATE INDEX entry_id_aliases_entry_idx ON entry_id_aliases (entry_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS entries_visibility_idx;
+ALTER TABLE temp_insert_entries DROP COLUMN IF EXISTS embargo_until;
+ALTER TABLE temp_insert_entries DROP COLUMN IF EXISTS visibility;
+ALTER TABLE entries DROP COLUMN IF EXISTS embargo_until;
+ALTER TABLE entries DROP COLUMN IF EXISTS visibility;
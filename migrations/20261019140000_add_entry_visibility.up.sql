@@ -0,0 +1,8 @@
+ALTER TABLE entries ADD COLUMN visibility text NOT NULL DEFAULT 'published';
+ALTER TABLE entries ADD COLUMN embargo_until date;
+
+-- blank means the sheet didn't say, so existing entries keep what they have
+ALTER TABLE temp_insert_entries ADD COLUMN visibility text NOT NULL DEFAULT '';
+ALTER TABLE temp_insert_entries ADD COLUMN embargo_until date;
+
+CREATE INDEX entries_visibility_idx ON entries (visibility, embargo_until);
@@ -0,0 +1,18 @@
+CREATE TABLE submissions (
+    id serial PRIMARY KEY,
+    -- given to the submitter so they can check on their submission
+    token text NOT NULL UNIQUE,
+    status text NOT NULL DEFAULT 'pending',
+    submitted_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    submitter_name text NOT NULL DEFAULT '',
+    submitter_email text NOT NULL DEFAULT '',
+    submitter_ip text NOT NULL DEFAULT '',
+    fields jsonb NOT NULL DEFAULT '{}',
+    files jsonb NOT NULL DEFAULT '[]',
+    entry_id text,
+    review_note text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX submissions_status_idx ON submissions (status, submitted_at);
+CREATE INDEX submissions_ip_idx ON submissions (submitter_ip, submitted_at);
@@ -0,0 +1,18 @@
+CREATE TABLE entry_reports (
+    id serial PRIMARY KEY,
+    -- not a foreign key, so reports outlive the entry they're about
+    entry_id text COLLATE numeric NOT NULL,
+    category text NOT NULL,
+    field text NOT NULL DEFAULT '',
+    message text NOT NULL,
+    status text NOT NULL DEFAULT 'open',
+    reported_at timestamptz NOT NULL DEFAULT now(),
+    reporter_ip text NOT NULL DEFAULT '',
+    resolved_at timestamptz,
+    resolved_by text NOT NULL DEFAULT '',
+    resolution_note text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX entry_reports_status_idx ON entry_reports (status, reported_at);
+CREATE INDEX entry_reports_entry_idx ON entry_reports (entry_id);
+CREATE INDEX entry_reports_ip_idx ON entry_reports (reporter_ip, reported_at);
@@ -0,0 +1,3 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX entries_title_trgm_idx ON entries USING GIN (title gin_trgm_ops);
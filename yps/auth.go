@@ -105,6 +105,27 @@ func extractBearerToken(header string) (string, error) {
 	return jwtToken[1], nil
 }
 
+func parseTokenLevel(tokenString string) (string, error) {
+	parser := paseto.NewParser()
+	parser.AddRule(paseto.NotExpired())
+	parser.AddRule(paseto.ValidAt(time.Now()))
+
+	token, err := parser.ParseV4Local(TheAuth.key, tokenString, nil)
+	if err != nil {
+		return "", err
+	}
+
+	level, err := token.GetString("level")
+	if err != nil {
+		return "", errors.New("token doesn't include 'level' claim")
+	}
+	if level != "admin" && level != "superuser" {
+		return "", fmt.Errorf("unknown level [%s]", level)
+	}
+
+	return level, nil
+}
+
 func AdminAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString, err := extractBearerToken(c.GetHeader("Authorization"))
@@ -113,27 +134,38 @@ func AdminAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		parser := paseto.NewParser()
-		parser.AddRule(paseto.NotExpired())
-		parser.AddRule(paseto.ValidAt(time.Now()))
-
-		token, err := parser.ParseV4Local(TheAuth.key, tokenString, nil)
+		level, err := parseTokenLevel(tokenString)
 		if err != nil {
 			fmt.Println("Token error:", err)
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"status": "unauthorized"})
 			return
 		}
 
-		level, err := token.GetString("level")
+		c.Set("level", level)
+	}
+}
+
+// OptionalAuthMiddleware lets anyone through, but notes when an admin's
+// calling so public endpoints can show them more.
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString, err := extractBearerToken(c.GetHeader("Authorization"))
 		if err != nil {
-			fmt.Println("Token doesn't include 'level' claim")
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"status": "unauthorized"})
+			return
 		}
 
-		if level == "admin" || level == "superuser" {
-			// continue!!
-		} else {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"status": "unauthorized"})
+		level, err := parseTokenLevel(tokenString)
+		if err != nil {
+			fmt.Println("Token error:", err)
+			return
 		}
+
+		c.Set("level", level)
 	}
 }
+
+// isAdminRequest returns whether the caller has a valid admin token.
+func isAdminRequest(c *gin.Context) bool {
+	level := c.GetString("level")
+	return level == "admin" || level == "superuser"
+}
@@ -0,0 +1,104 @@
+package yps
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// organisations like 'Youth, Peace and Security Network' show up as authors,
+// so 'and' and commas aren't separators by default
+var DefaultAuthorSeparators = []string{";"}
+
+var (
+	authorSeparators     []string
+	authorSeparatorRegex *regexp.Regexp
+	authorsEtAlRegex     = regexp.MustCompile(`(?i)[\s,;]*\b(et\.?\s*al\.?|and others)\s*$`)
+)
+
+func init() {
+	SetAuthorSeparators(DefaultAuthorSeparators)
+}
+
+// SetAuthorSeparators sets what the authors column gets split on. Separators
+// made of letters, like 'and', only match as whole words. New lines always
+// separate authors.
+func SetAuthorSeparators(separators []string) {
+	authorSeparators = nil
+	patterns := []string{`\s*[\r\n]+\s*`}
+	for _, separator := range separators {
+		separator = strings.TrimSpace(separator)
+		if separator == "" {
+			continue
+		}
+		authorSeparators = append(authorSeparators, separator)
+
+		isWord := strings.IndexFunc(separator, func(r rune) bool {
+			return !unicode.IsLetter(r)
+		}) == -1
+		if isWord {
+			patterns = append(patterns, `\s+`+regexp.QuoteMeta(separator)+`\s+`)
+		} else {
+			patterns = append(patterns, `\s*`+regexp.QuoteMeta(separator)+`\s*`)
+		}
+	}
+	authorSeparatorRegex = regexp.MustCompile(`(?i)` + strings.Join(patterns, "|"))
+}
+
+// SplitAuthors splits the authors column into individual names, and says
+// whether it ended with 'et al.'.
+func SplitAuthors(input string) (authors []string, etAl bool) {
+	input = strings.TrimSpace(input)
+	if authorsEtAlRegex.MatchString(input) {
+		etAl = true
+		input = authorsEtAlRegex.ReplaceAllString(input, "")
+	}
+
+	for _, author := range authorSeparatorRegex.Split(input, -1) {
+		author = strings.Join(strings.Fields(author), " ")
+		if author == "" || author == "0" || slices.Contains(authors, author) {
+			continue
+		}
+		authors = append(authors, author)
+	}
+
+	return authors, etAl
+}
+
+// JoinAuthors returns an authors column value that SplitAuthors reads back into the same list.
+func JoinAuthors(authors []string, etAl bool) string {
+	separator := ";"
+	if len(authorSeparators) > 0 && !slices.Contains(authorSeparators, ";") {
+		separator = " " + authorSeparators[0]
+	}
+
+	joined := strings.Join(authors, separator+" ")
+	if etAl {
+		joined += " et al."
+	}
+	return joined
+}
+
+// handler
+
+type GetAuthorsResponse struct {
+	Authors []SearchFilterValue `json:"authors"`
+}
+
+func getAuthors(c *gin.Context) {
+	authors, err := TheDb.GetAuthors(isAdminRequest(c))
+	if err != nil {
+		fmt.Println("Could not get authors:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get authors"})
+		return
+	}
+
+	c.JSON(http.StatusOK, GetAuthorsResponse{
+		Authors: authors,
+	})
+}
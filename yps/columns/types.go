@@ -1,6 +1,9 @@
 package ypsc
 
-import "strings"
+import (
+	"slices"
+	"strings"
+)
 
 type ColumnType int
 
@@ -33,10 +36,13 @@ const (
 	RegionWestCentralAfrica
 	RegionGlobal
 	RegionNA
+	PreviousIDs
+	Visibility
+	Notes
 )
 
 func (ct ColumnType) String() string {
-	return [...]string{"None", "Item", "Authors", "Year", "Title", "Org / Publisher", "Doc #", "Day / Month", "URL", "Languages available", "Alternate Languages", "Related Documents", "Youth-led/ authored", "Abstract/ Exec Summary", "Type of org", "Type of document", "Keywords", "East and Southern Africa", "East and Central Asia", "Southeast Asia and the Pacific", "Europe and Eurasia", "Latin America and the Caribbean", "Middle East and North Africa", "North America", "South Asia", "West and Central Africa", "Global", "N/A"}[ct]
+	return [...]string{"None", "Item", "Authors", "Year", "Title", "Org / Publisher", "Doc #", "Day / Month", "URL", "Languages available", "Alternate Languages", "Related Documents", "Youth-led/ authored", "Abstract/ Exec Summary", "Type of org", "Type of document", "Keywords", "East and Southern Africa", "East and Central Asia", "Southeast Asia and the Pacific", "Europe and Eurasia", "Latin America and the Caribbean", "Middle East and North Africa", "North America", "South Asia", "West and Central Africa", "Global", "N/A", "Previous IDs", "Visibility", "Notes"}[ct]
 }
 
 var RequiredColumns = []ColumnType{
@@ -47,6 +53,12 @@ var RequiredColumns = []ColumnType{
 	RegionNorthAmerica, RegionSouthAsia, RegionWestCentralAfrica, RegionGlobal, RegionNA,
 }
 
+// OptionalColumns are read if they're in the sheet, but older sheets don't need them.
+var OptionalColumns = []ColumnType{PreviousIDs, Visibility, Notes}
+
+// SheetColumns are all the columns, in the order exports and templates list them.
+var SheetColumns = append(slices.Clone(RequiredColumns), OptionalColumns...)
+
 func ColumnNames(input ...ColumnType) string {
 	var names []string
 
@@ -56,3 +68,10 @@ func ColumnNames(input ...ColumnType) string {
 
 	return strings.Join(names, ", ")
 }
+
+// RegionColumns are the 0/1 flag columns, named after the region they mark.
+var RegionColumns = []ColumnType{
+	RegionEastSouthAfrica, RegionEastCentralAsia, RegionSouthEastAsiaPacific,
+	RegionEuropeEurasia, RegionLatinAmericaCaribbean, RegionMiddleEastNorthAfrica,
+	RegionNorthAmerica, RegionSouthAsia, RegionWestCentralAfrica, RegionGlobal, RegionNA,
+}
@@ -22,6 +22,9 @@ type Config struct {
 	UploadS3Bucket         string `env:"UPLOAD_S3_BUCKET, required"`
 	UploadS3KeyPrefix      string `env:"UPLOAD_KEY_PREFIX, required"`
 	UploadS3URLPrefix      string `env:"UPLOAD_URL_PREFIX, required"`
+	PrivateS3KeyPrefix     string `env:"PRIVATE_KEY_PREFIX, default=private/"`
+	AuthorSeparators       string `env:"AUTHOR_SEPARATORS"`
+	TranslationTargets     string `env:"TRANSLATION_TARGETS"`
 }
 
 func LoadConfig() (config Config, err error) {
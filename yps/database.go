@@ -85,6 +85,98 @@ OFFSET $2
 	return logs, err
 }
 
+// import lock
+
+// AcquireImportLock takes the global import lock. If someone else is already
+// holding it, lock is nil and the current holder is returned instead.
+func (db *YPSDatabase) AcquireImportLock(holder, action string) (lock *ImportLock, current *ImportLockHolder, err error) {
+	// advisory locks belong to the session, so we keep this connection until the lock is released
+	conn, err := db.pool.Acquire(context.Background())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var acquired bool
+	err = conn.QueryRow(context.Background(), `select pg_try_advisory_lock($1)`, ImportAdvisoryLockKey).Scan(&acquired)
+	if err != nil {
+		conn.Release()
+		return nil, nil, err
+	}
+
+	if !acquired {
+		conn.Release()
+		current, err = db.GetImportLockHolder()
+		if current == nil && err == nil {
+			// it was released between us trying to take it and looking it up
+			current = &ImportLockHolder{
+				Holder: "unknown",
+				Action: "unknown",
+				Since:  time.Now().UTC(),
+			}
+		}
+		return nil, current, err
+	}
+
+	lock = &ImportLock{
+		conn: conn,
+		Holder: ImportLockHolder{
+			Holder: holder,
+			Action: action,
+			Since:  time.Now().UTC(),
+		},
+	}
+
+	_, err = conn.Exec(context.Background(), `
+insert into import_lock (id, holder, action, acquired_at)
+values (true, $1, $2, $3)
+on conflict (id)
+do update
+set
+	holder=excluded.holder,
+	action=excluded.action,
+	acquired_at=excluded.acquired_at
+`, holder, action, lock.Holder.Since)
+	if err != nil {
+		lock.Release()
+		return nil, nil, err
+	}
+
+	return lock, nil, nil
+}
+
+// GetImportLockHolder returns who's holding the import lock, or nil if nobody is.
+func (db *YPSDatabase) GetImportLockHolder() (holder *ImportLockHolder, err error) {
+	var h ImportLockHolder
+	var held bool
+
+	err = db.pool.QueryRow(context.Background(), `
+select exists (
+	select from pg_locks
+	where locktype = 'advisory' and classid = 0 and objid = $1 and objsubid = 1 and granted
+)
+`, ImportAdvisoryLockKey).Scan(&held)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Import lock QueryRow failed: %v\n", err)
+		return nil, err
+	}
+	if !held {
+		return nil, nil
+	}
+
+	err = db.pool.QueryRow(context.Background(), `
+select holder, action, acquired_at
+from import_lock
+`).Scan(&h.Holder, &h.Action, &h.Since)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "Import lock holder QueryRow failed: %v\n", err)
+		return nil, err
+	}
+
+	return &h, nil
+}
+
 // db files
 
 func (db *YPSDatabase) UploadDbFile(filename string, body io.Reader) error {
@@ -128,18 +220,20 @@ set
 	return err
 }
 
-func (db *YPSDatabase) GetLatestDbInfo() (info ypsDbInfo, err error) {
-	err = db.pool.QueryRow(context.Background(), `
-select count(*) from entries
-`).Scan(&info.NumberOfEntries)
+func (db *YPSDatabase) GetLatestDbInfo(includeNonPublic bool) (info ypsDbInfo, err error) {
+	visibleFilter := visibleEntriesFilter(includeNonPublic)
+
+	err = db.pool.QueryRow(context.Background(), fmt.Sprintf(`
+select count(*) from entries where %s
+`, visibleFilter)).Scan(&info.NumberOfEntries)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Count QueryRow failed: %v\n", err)
 		return info, err
 	}
 
-	err = db.pool.QueryRow(context.Background(), `
-select count(distinct entry_language) from entries
-`).Scan(&info.NumberOfLanguages)
+	err = db.pool.QueryRow(context.Background(), fmt.Sprintf(`
+select count(distinct entry_language) from entries where %s
+`, visibleFilter)).Scan(&info.NumberOfLanguages)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Count QueryRow failed: %v\n", err)
 		return info, err
@@ -183,13 +277,14 @@ order by added_at desc
 // entries
 //
 
-func (db *YPSDatabase) GetBrowseByFields() (values BrowseByFieldValues, err error) {
+func (db *YPSDatabase) GetBrowseByFields(includeNonPublic bool) (values BrowseByFieldValues, err error) {
 	values = make(BrowseByFieldValues)
+	visibleFilter := visibleEntriesFilter(includeNonPublic)
 
 	// youth-led
-	rows, err := db.pool.Query(context.Background(), `
-select distinct youth_led from entries order by youth_led desc
-`)
+	rows, err := db.pool.Query(context.Background(), fmt.Sprintf(`
+select distinct youth_led from entries where %s order by youth_led desc
+`, visibleFilter))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Youth-led query failed: %v\n", err)
 		return values, err
@@ -210,9 +305,12 @@ select distinct youth_led from entries order by youth_led desc
 	}
 
 	// year
-	rows, err = db.pool.Query(context.Background(), `
-select distinct DATE_PART('year', start_date) AS year from entries where start_date > '1800-01-01' order by year desc
-`)
+	rows, err = db.pool.Query(context.Background(), fmt.Sprintf(`
+select distinct generate_series(DATE_PART('year', start_date)::int, DATE_PART('year', end_date)::int) AS year
+from entries
+where date_precision <> 'unknown' and start_date > '1800-01-01' and %s
+order by year desc
+`, visibleFilter))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Year query failed: %v\n", err)
 		return values, err
@@ -233,10 +331,10 @@ select distinct DATE_PART('year', start_date) AS year from entries where start_d
 	}
 
 	// entry type
-	rows, err = db.pool.Query(context.Background(), `
-select entry_type, count(*) as number_of_rows from entries group by entry_type order by entry_type asc
+	rows, err = db.pool.Query(context.Background(), fmt.Sprintf(`
+select entry_type, count(*) as number_of_rows from entries where %s group by entry_type order by entry_type asc
 -- number_of_rows desc
-`)
+`, visibleFilter))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Entry type query failed: %v\n", err)
 		return values, err
@@ -261,9 +359,9 @@ select entry_type, count(*) as number_of_rows from entries group by entry_type o
 	}
 
 	// regions
-	rows, err = db.pool.Query(context.Background(), `
-	select distinct unnest(regions) as region_name from entries order by region_name asc
-	`)
+	rows, err = db.pool.Query(context.Background(), fmt.Sprintf(`
+	select distinct unnest(regions) as region_name from entries where %s order by region_name asc
+	`, visibleFilter))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Entry type query failed: %v\n", err)
 		return values, err
@@ -290,13 +388,68 @@ select entry_type, count(*) as number_of_rows from entries group by entry_type o
 	return values, err
 }
 
+func (db *YPSDatabase) GetDistinctEntryValues(column string) (values []string, err error) {
+	if column != "entry_type" && column != "org_type" {
+		return values, fmt.Errorf("cannot get distinct values for column [%s]", column)
+	}
+
+	rows, err := db.pool.Query(context.Background(), fmt.Sprintf(`
+select distinct %s from entries where %s <> '' order by %s asc
+`, column, column, column))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Distinct values query failed: %v\n", err)
+		return values, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var value string
+		err = rows.Scan(&value)
+		if err != nil {
+			return values, err
+		}
+		values = append(values, value)
+	}
+
+	return values, err
+}
+
+func (db *YPSDatabase) GetAuthors(includeNonPublic bool) (authors []SearchFilterValue, err error) {
+	authors = []SearchFilterValue{}
+
+	rows, err := db.pool.Query(context.Background(), fmt.Sprintf(`
+select author_name, count(*)
+from entries cross join lateral
+  unnest(entries.authors) author_name
+where %s
+group by author_name
+order by lower(author_name) asc
+`, visibleEntriesFilter(includeNonPublic)))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Authors query failed: %v\n", err)
+		return authors, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var author SearchFilterValue
+		err = rows.Scan(&author.Value, &author.Count)
+		if err != nil {
+			return authors, err
+		}
+		authors = append(authors, author)
+	}
+
+	return authors, err
+}
+
 func (db *YPSDatabase) GetAllEntries() (entries map[string]Entry, err error) {
 	entries = make(map[string]Entry)
 
 	rows, err := db.pool.Query(context.Background(), `
-select id, url, entry_type, entry_language, start_date, end_date, alternates,
-	related, title, authors, abstract, keywords, regions, orgs, org_doc_id, org_type,
-	youth_led, youth_led_distilled
+select id, url, entry_type, entry_language, start_date, end_date, date_precision, alternates,
+	related, title, authors, authors_et_al, abstract, keywords, regions, orgs, org_doc_id, org_type,
+	youth_led, youth_led_distilled, manually_edited_at, visibility, embargo_until, coalesce(notes, '')
 from entries
 `)
 	if err != nil {
@@ -309,48 +462,130 @@ from entries
 		var e Entry
 
 		err = rows.Scan(&e.ItemID, &e.URL, &e.DocType, &e.Language, &e.StartDate, &e.EndDate,
-			&e.AltLanguageIDs, &e.RelatedIDs, &e.Title, &e.Authors, &e.Abstract, &e.Keywords,
-			&e.Regions, &e.OrgPublishers, &e.OrgDocID, &e.OrgType, &e.YouthLedDetails, &e.YouthLed)
+			&e.DatePrecision, &e.AltLanguageIDs, &e.RelatedIDs, &e.Title, &e.Authors, &e.AuthorsEtAl, &e.Abstract, &e.Keywords,
+			&e.Regions, &e.OrgPublishers, &e.OrgDocID, &e.OrgType, &e.YouthLedDetails, &e.YouthLed, &e.ManuallyEditedAt,
+			&e.Visibility, &e.EmbargoUntil, &e.Notes)
 		if err != nil {
 			return entries, err
 		}
+		e.DateDisplay = FormatEntryDate(e.StartDate, e.EndDate, e.DatePrecision)
 		entries[e.ItemID] = e
 	}
+	rows.Close()
+
+	relations, err := db.GetAllEntryRelations()
+	if err != nil {
+		return entries, err
+	}
+	for id, entryRelations := range relations {
+		e, exists := entries[id]
+		if exists {
+			e.Relations = entryRelations
+			entries[id] = e
+		}
+	}
+
+	aliases, err := db.GetAllEntryAliases()
+	if err != nil {
+		return entries, err
+	}
+	for id, previousIDs := range aliases {
+		e, exists := entries[id]
+		if exists {
+			e.PreviousIDs = previousIDs
+			entries[id] = e
+		}
+	}
 
 	return entries, err
 }
 
-func (db *YPSDatabase) GetSingleEntry(id string) (entry LookedUpEntry, err error) {
-	entry.Files = []EntryFile{}
-	entry.Alternates = make(map[string]LookedUpAltLanguageEntry)
-	entry.Related = make(map[string]string)
+// GetSingleEntry looks up the entry and the entries it links to. Entries the
+// public can't see are treated as missing unless includeNonPublic is set.
+func (db *YPSDatabase) GetSingleEntry(id string, includeNonPublic bool) (entry LookedUpEntry, err error) {
+	entries, err := db.GetEntries([]string{id}, includeNonPublic)
+	if err != nil {
+		return entry, err
+	}
+
+	entry, exists := entries[id]
+	if !exists {
+		return entry, sql.ErrNoRows
+	}
+	return entry, nil
+}
 
-	// get the main entry
-	err = db.pool.QueryRow(context.Background(), `
-select id, url, entry_type, entry_language, start_date, end_date, alternates, related, title, authors, abstract, keywords, regions, orgs, org_doc_id, org_type, youth_led, youth_led_distilled
+// GetEntries looks up the given entries and the entries they link to, with a
+// fixed number of queries however many are asked for. Entries that don't
+// exist, or that the public can't see unless includeNonPublic is set, are
+// left out of the result.
+func (db *YPSDatabase) GetEntries(ids []string, includeNonPublic bool) (entries map[string]LookedUpEntry, err error) {
+	visibleFilter := visibleEntriesFilter(includeNonPublic)
+	entries = make(map[string]LookedUpEntry)
+
+	// get the main entries
+	rows, err := db.pool.Query(context.Background(), fmt.Sprintf(`
+select id, url, entry_type, entry_language, start_date, end_date, date_precision, alternates, related, title, authors, authors_et_al, abstract, keywords, regions, orgs, org_doc_id, org_type, youth_led, youth_led_distilled, manually_edited_at, visibility, embargo_until, coalesce(notes, ''),
+	array(select alias from entry_id_aliases where entry_id=entries.id order by alias)
 from entries
-where id=$1
-`, id).Scan(
-		&entry.Entry.ItemID, &entry.Entry.URL, &entry.Entry.DocType, &entry.Entry.Language, &entry.Entry.StartDate,
-		&entry.Entry.EndDate, &entry.Entry.AltLanguageIDs, &entry.Entry.RelatedIDs, &entry.Entry.Title,
-		&entry.Entry.Authors, &entry.Entry.Abstract, &entry.Entry.Keywords, &entry.Entry.Regions,
-		&entry.Entry.OrgPublishers, &entry.Entry.OrgDocID, &entry.Entry.OrgType, &entry.Entry.YouthLedDetails,
-		&entry.Entry.YouthLed,
-	)
+where id=any($1) and %s
+`, visibleFilter), ids)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "QueryRow for entry failed: %v\n", err)
-		return entry, err
+		fmt.Fprintf(os.Stderr, "Query for entries failed: %v\n", err)
+		return entries, err
+	}
+	var altIDs, relatedIDs []string
+	for rows.Next() {
+		var entry LookedUpEntry
+		entry.Files = []EntryFile{}
+		entry.Alternates = make(map[string]LookedUpAltLanguageEntry)
+		entry.Related = make(map[string]string)
+		entry.ReferencedBy = make(map[string]string)
+		entry.Relations = []LookedUpRelationGroup{}
+
+		err = rows.Scan(
+			&entry.Entry.ItemID, &entry.Entry.URL, &entry.Entry.DocType, &entry.Entry.Language, &entry.Entry.StartDate,
+			&entry.Entry.EndDate, &entry.Entry.DatePrecision, &entry.Entry.AltLanguageIDs, &entry.Entry.RelatedIDs, &entry.Entry.Title,
+			&entry.Entry.Authors, &entry.Entry.AuthorsEtAl, &entry.Entry.Abstract, &entry.Entry.Keywords, &entry.Entry.Regions,
+			&entry.Entry.OrgPublishers, &entry.Entry.OrgDocID, &entry.Entry.OrgType, &entry.Entry.YouthLedDetails,
+			&entry.Entry.YouthLed, &entry.Entry.ManuallyEditedAt, &entry.Entry.Visibility, &entry.Entry.EmbargoUntil,
+			&entry.Entry.Notes, &entry.Entry.PreviousIDs,
+		)
+		if err != nil {
+			rows.Close()
+			return entries, err
+		}
+		entry.Entry.DateDisplay = FormatEntryDate(entry.Entry.StartDate, entry.Entry.EndDate, entry.Entry.DatePrecision)
+
+		// curator notes are private
+		if !includeNonPublic {
+			entry.Entry.Notes = ""
+		}
+
+		entries[entry.Entry.ItemID] = entry
+		altIDs = append(altIDs, entry.Entry.AltLanguageIDs...)
+		relatedIDs = append(relatedIDs, entry.Entry.RelatedIDs...)
+	}
+	rows.Close()
+	if len(entries) == 0 {
+		return entries, rows.Err()
+	}
+
+	foundIDs := make([]string, 0, len(entries))
+	for id := range entries {
+		foundIDs = append(foundIDs, id)
 	}
 
 	// get the alternate languages
-	rows, err := db.pool.Query(context.Background(), `
+	alternates := make(map[string]LookedUpAltLanguageEntry)
+	rows, err = db.pool.Query(context.Background(), fmt.Sprintf(`
 select id, entry_language, title
 from entries
-where id=any($1)
-`, entry.Entry.AltLanguageIDs)
+where id=any($1) and %s
+`, visibleFilter), altIDs)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Query for alt rows failed: %v\n", err)
-		return entry, err
+		return entries, err
 	}
 	for rows.Next() {
 		var leID string
@@ -359,95 +594,193 @@ where id=any($1)
 
 		err = rows.Scan(&leID, &le.Language, &le.Title)
 		if err != nil {
-			return entry, err
+			rows.Close()
+			return entries, err
 		}
 
-		if leID != id {
-			entry.Alternates[leID] = le
-		}
+		alternates[leID] = le
 	}
 	rows.Close()
 
 	// get all files
-	all_ids := slices.Concat(entry.Entry.AltLanguageIDs, []string{id}) // not necessary, but just in case
-
+	entryFiles := make(map[string][]EntryFile)
 	rows, err = db.pool.Query(context.Background(), `
 select entry_id, filename, url
 from entry_files
 where entry_id=any($1)
-`, all_ids)
+`, slices.Concat(foundIDs, altIDs))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Query for files failed: %v\n", err)
-		return entry, err
+		return entries, err
 	}
 	for rows.Next() {
-		var entryID, filename, url string
+		var entryID string
+		var file EntryFile
 
-		err = rows.Scan(&entryID, &filename, &url)
+		err = rows.Scan(&entryID, &file.Filename, &file.URL)
 		if err != nil {
-			return entry, err
+			rows.Close()
+			return entries, err
 		}
 
-		newFile := EntryFile{
-			Filename: filename,
-			URL:      url,
-		}
-		if entryID == id {
-			entry.Files = append(entry.Files, newFile)
-		} else {
-			for leID, le := range entry.Alternates {
-				if entryID == leID {
-					le.Files = append(le.Files, newFile)
-					entry.Alternates[leID] = le
-				}
-			}
-		}
+		entryFiles[entryID] = append(entryFiles[entryID], file)
 	}
 	rows.Close()
 
 	// get the related entries
-	rows, err = db.pool.Query(context.Background(), `
+	relatedTitles := make(map[string]string)
+	rows, err = db.pool.Query(context.Background(), fmt.Sprintf(`
 select id, title
 from entries
-where id=any($1)
-`, entry.Entry.RelatedIDs)
+where id=any($1) and %s
+`, visibleFilter), relatedIDs)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Query for alt rows failed: %v\n", err)
-		return entry, err
+		fmt.Fprintf(os.Stderr, "Query for related rows failed: %v\n", err)
+		return entries, err
 	}
 	for rows.Next() {
 		var reID, reTitle string
 
 		err = rows.Scan(&reID, &reTitle)
 		if err != nil {
-			return entry, err
+			rows.Close()
+			return entries, err
+		}
+
+		relatedTitles[reID] = reTitle
+	}
+	rows.Close()
+
+	for id, entry := range entries {
+		if files, exists := entryFiles[id]; exists {
+			entry.Files = files
+		}
+		for _, leID := range entry.Entry.AltLanguageIDs {
+			le, exists := alternates[leID]
+			if !exists || leID == id {
+				continue
+			}
+			if files, exists := entryFiles[leID]; exists {
+				le.Files = files
+			}
+			entry.Alternates[leID] = le
+		}
+		for _, reID := range entry.Entry.RelatedIDs {
+			if reTitle, exists := relatedTitles[reID]; exists && reID != id {
+				entry.Related[reID] = reTitle
+			}
+		}
+		entries[id] = entry
+	}
+
+	// get the entries that point at each one without being pointed back to
+	rows, err = db.pool.Query(context.Background(), fmt.Sprintf(`
+select target, id, title
+from entries
+cross join lateral unnest(related) target
+where target = any($1) and id <> target and %s
+`, visibleFilter), foundIDs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Query for backlink rows failed: %v\n", err)
+		return entries, err
+	}
+	for rows.Next() {
+		var targetID, reID, reTitle string
+
+		err = rows.Scan(&targetID, &reID, &reTitle)
+		if err != nil {
+			rows.Close()
+			return entries, err
+		}
+
+		entry := entries[targetID]
+		if !slices.Contains(entry.Entry.RelatedIDs, reID) {
+			entry.ReferencedBy[reID] = reTitle
+		}
+	}
+	rows.Close()
+
+	// get typed relations both ways, from the side of each entry asked for
+	rows, err = db.pool.Query(context.Background(), fmt.Sprintf(`
+select r.relation_type, t.label, t.inverse_label, t.symmetric, side.outgoing, side.self_id, side.other_id, e.title
+from entry_relations r
+join relation_types t on t.name = r.relation_type
+cross join lateral (values (r.entry_id, true, r.related_id), (r.related_id, false, r.entry_id)) side(self_id, outgoing, other_id)
+join entries e on e.id = side.other_id
+where side.self_id = any($1) and (side.outgoing or r.entry_id <> r.related_id) and %s
+order by r.relation_type asc
+`, visibleFilter), foundIDs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Query for relation rows failed: %v\n", err)
+		return entries, err
+	}
+	for rows.Next() {
+		var relationType RelationType
+		var outgoing bool
+		var selfID, otherID, title string
+
+		err = rows.Scan(&relationType.Name, &relationType.Label, &relationType.InverseLabel, &relationType.Symmetric, &outgoing, &selfID, &otherID, &title)
+		if err != nil {
+			rows.Close()
+			return entries, err
+		}
+
+		group := LookedUpRelationGroup{
+			Type:    relationType.Name,
+			Label:   relationType.Label,
+			Entries: make(map[string]string),
+		}
+		if !outgoing && !relationType.Symmetric {
+			group.Inverse = true
+			group.Label = relationType.InverseLabel
 		}
 
-		if reID != id {
-			entry.Related[reID] = reTitle
+		entry := entries[selfID]
+		groupIndex := slices.IndexFunc(entry.Relations, func(existing LookedUpRelationGroup) bool {
+			return existing.Type == group.Type && existing.Inverse == group.Inverse
+		})
+		if groupIndex == -1 {
+			entry.Relations = append(entry.Relations, group)
+			groupIndex = len(entry.Relations) - 1
 		}
+		entry.Relations[groupIndex].Entries[otherID] = title
+		entries[selfID] = entry
 	}
 	rows.Close()
 
 	//TODO(dan): look up related files for this language and others
 
-	return entry, err
+	return entries, rows.Err()
 }
 
-func (db *YPSDatabase) UploadEntries(entryMap map[string]XlsxEntry) error {
+func (db *YPSDatabase) UploadEntries(entryMap map[string]XlsxEntry, mode ImportMode, change EntryChange, progress ImportProgressFunc) error {
+	if progress == nil {
+		progress = func(phase ImportPhase, progress float64) {}
+	}
+
 	// assemble rows slice
 	var rows [][]any
 	for id, entry := range entryMap {
 		startDate, _ := time.Parse(time.DateOnly, entry.StartDate)
 		endDate, _ := time.Parse(time.DateOnly, entry.EndDate)
+		var embargoUntil *time.Time
+		if entry.EmbargoUntil != "" {
+			date, _ := time.Parse(time.DateOnly, entry.EmbargoUntil)
+			embargoUntil = &date
+		}
 		rows = append(rows, []any{
-			id, entry.URL, entry.DocType, entry.Language, startDate, endDate,
-			entry.AltLanguageIDs, entry.RelatedIDs, entry.Title, entry.Authors, entry.Abstract,
+			id, entry.URL, entry.DocType, entry.Language, startDate, endDate, entry.DatePrecision,
+			entry.AltLanguageIDs, entry.RelatedIDs, entry.Title, entry.Authors, entry.AuthorsEtAl, entry.Abstract,
 			entry.Keywords, entry.Regions, entry.OrgPublishers, entry.OrgDocID, entry.OrgType,
-			entry.YouthLed, entry.YouthLedDetails,
+			entry.YouthLed, entry.YouthLedDetails, string(entry.Visibility), embargoUntil, entry.Notes,
 		})
 	}
-	source := pgx.CopyFromRows(rows)
+	source := pgx.CopyFromSlice(len(rows), func(i int) ([]any, error) {
+		progress(ImportPhaseCopying, float64(i)/float64(len(rows)))
+		return rows[i], nil
+	})
+
+	progress(ImportPhaseCopying, 0)
 
 	// delete rows from temp table
 	_, err := db.pool.Exec(context.Background(), `truncate table temp_insert_entries`)
@@ -457,9 +790,9 @@ func (db *YPSDatabase) UploadEntries(entryMap map[string]XlsxEntry) error {
 
 	fmt.Println("starting copy into temp table")
 	_, err = db.pool.CopyFrom(context.Background(), pgx.Identifier{`temp_insert_entries`}, []string{
-		"id", "url", "entry_type", "entry_language", "start_date", "end_date", "alternates",
-		"related", "title", "authors", "abstract", "keywords", "regions", "orgs", "org_doc_id",
-		"org_type", "youth_led_distilled", "youth_led"}, source)
+		"id", "url", "entry_type", "entry_language", "start_date", "end_date", "date_precision", "alternates",
+		"related", "title", "authors", "authors_et_al", "abstract", "keywords", "regions", "orgs", "org_doc_id",
+		"org_type", "youth_led_distilled", "youth_led", "visibility", "embargo_until", "notes"}, source)
 	fmt.Println("ended copy into temp table")
 
 	if err != nil {
@@ -467,10 +800,50 @@ func (db *YPSDatabase) UploadEntries(entryMap map[string]XlsxEntry) error {
 	}
 
 	// transfer rows to real table
-	_, err = db.pool.Exec(context.Background(), `
-insert into entries (id, url, entry_type, entry_language, start_date, end_date, alternates, related, title, authors, abstract, keywords, regions, orgs, org_doc_id, org_type, youth_led, youth_led_distilled)
-select id, url, entry_type, entry_language, start_date, end_date, alternates, related, title, authors, abstract, keywords, regions, orgs, org_doc_id, org_type, youth_led, youth_led_distilled
+	progress(ImportPhaseUpserting, 0)
+	tx, err := db.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	err = setEntryChange(tx, change)
+	if err != nil {
+		return err
+	}
+
+	var relationEntryIDs []string
+	for id := range entryMap {
+		relationEntryIDs = append(relationEntryIDs, id)
+	}
+	if mode == ImportModeAppend {
+		// relations of the existing entries that get skipped are left alone too
+		err = tx.QueryRow(context.Background(), `
+select array(select unnest($1::text[]) except select id from entries)
+`, relationEntryIDs).Scan(&relationEntryIDs)
+		if err != nil {
+			return err
+		}
+	}
+
+	var insertFilter string
+	if mode == ImportModeAppend {
+		// existing entries are left alone entirely
+		insertFilter = `where not exists (
+	select from entries
+	where entries."id" = temp_insert_entries."id"
+)`
+	}
+	_, err = tx.Exec(context.Background(), fmt.Sprintf(`
+insert into entries (id, url, entry_type, entry_language, start_date, end_date, date_precision, alternates, related, title, authors, authors_et_al, abstract, keywords, regions, orgs, org_doc_id, org_type, youth_led, youth_led_distilled, visibility, embargo_until, notes)
+select id, url, entry_type, entry_language, start_date, end_date, date_precision, alternates, related, title, authors, authors_et_al, abstract, keywords, regions, orgs, org_doc_id, org_type, youth_led, youth_led_distilled,
+	-- a blank visibility keeps what the entry already has
+	case when temp_insert_entries.visibility = '' then coalesce((select e.visibility from entries e where e.id = temp_insert_entries.id), 'published') else temp_insert_entries.visibility end,
+	case when temp_insert_entries.visibility = '' then (select e.embargo_until from entries e where e.id = temp_insert_entries.id) else temp_insert_entries.embargo_until end,
+	-- as do notes, when the sheet doesn't have them
+	case when temp_insert_entries.notes is null then (select e.notes from entries e where e.id = temp_insert_entries.id) else nullif(temp_insert_entries.notes, '') end
 from temp_insert_entries
+%s
 on conflict (id)
 do update
 set
@@ -479,10 +852,12 @@ set
 	entry_language=excluded.entry_language,
 	start_date=excluded.start_date,
 	end_date=excluded.end_date,
+	date_precision=excluded.date_precision,
 	alternates=excluded.alternates,
 	related=excluded.related,
 	title=excluded.title,
 	authors=excluded.authors,
+	authors_et_al=excluded.authors_et_al,
 	abstract=excluded.abstract,
 	keywords=excluded.keywords,
 	regions=excluded.regions,
@@ -490,24 +865,110 @@ set
 	org_doc_id=excluded.org_doc_id,
 	org_type=excluded.org_type,
 	youth_led=excluded.youth_led,
-	youth_led_distilled=excluded.youth_led_distilled
-`)
+	youth_led_distilled=excluded.youth_led_distilled,
+	visibility=excluded.visibility,
+	embargo_until=excluded.embargo_until,
+	notes=excluded.notes,
+	manually_edited_at=null
+`, insertFilter))
+	if err != nil {
+		return err
+	}
+
+	// typed relations of the entries we've written
+	_, err = tx.Exec(context.Background(), `
+delete from entry_relations where entry_id = ANY($1)
+`, relationEntryIDs)
+	if err != nil {
+		return err
+	}
+	var relationRows [][]any
+	for _, id := range relationEntryIDs {
+		for _, relation := range entryMap[id].Relations {
+			relationRows = append(relationRows, []any{id, relation.ID, relation.Type})
+		}
+	}
+	_, err = tx.CopyFrom(
+		context.Background(),
+		pgx.Identifier{"entry_relations"},
+		[]string{"entry_id", "related_id", "relation_type"},
+		pgx.CopyFromRows(relationRows),
+	)
+	if err != nil {
+		return err
+	}
+
+	// previous IDs of the entries we've written, which are taken over from any
+	// other entry. entries from sheets without the column keep the ones they have
+	var previousIDEntryIDs, aliases, aliasEntryIDs []string
+	for _, id := range relationEntryIDs {
+		if entryMap[id].PreviousIDs == nil {
+			continue
+		}
+		previousIDEntryIDs = append(previousIDEntryIDs, id)
+		for _, previousID := range *entryMap[id].PreviousIDs {
+			aliases = append(aliases, previousID)
+			aliasEntryIDs = append(aliasEntryIDs, id)
+		}
+	}
+	_, err = tx.Exec(context.Background(), `
+delete from entry_id_aliases where entry_id = ANY($1)
+`, previousIDEntryIDs)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(context.Background(), `
+insert into entry_id_aliases (alias, entry_id)
+select * from unnest($1::text[], $2::text[])
+on conflict (alias)
+do update
+set entry_id=excluded.entry_id
+`, aliases, aliasEntryIDs)
+	if err != nil {
+		return err
+	}
+
+	// entries that are back aren't gone anymore
+	_, err = tx.Exec(context.Background(), `
+delete from entry_tombstones where id = ANY($1)
+`, relationEntryIDs)
 	if err != nil {
 		return err
 	}
 
 	// remove rows in real table but not in temp table
-	_, err = db.pool.Exec(context.Background(), `
-delete from entries
-where not exists (
-	select from temp_insert_entries
-	where temp_insert_entries."id" = entries."id"
+	if mode == ImportModeReplace {
+		progress(ImportPhaseDeleting, 0)
+		_, err = tx.Exec(context.Background(), `
+with deleted as (
+	delete from entries
+	where not exists (
+		select from temp_insert_entries
+		where temp_insert_entries."id" = entries."id"
+	)
+	returning id
 )
-`)
+insert into entry_tombstones (id, reason, replaced_by)
+select id, $1, (select entry_id from entry_id_aliases where alias=deleted.id)
+from deleted
+on conflict (id)
+do update
+set
+	deleted_at=now(),
+	reason=excluded.reason,
+	replaced_by=excluded.replaced_by
+`, "Removed by "+change.Source)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit(context.Background())
 	if err != nil {
 		return err
 	}
 
+	progress(ImportPhaseBrowseFields, 0)
 	err = UpdateBrowseByFields()
 
 	return err
@@ -566,6 +1027,34 @@ func (db *YPSDatabase) ImportFileList(fileList FileList) (err error) {
 	return err
 }
 
+func (db *YPSDatabase) GetAllEntryFiles() (files map[string][]EntryFile, err error) {
+	files = make(map[string][]EntryFile)
+
+	rows, err := db.pool.Query(context.Background(), `
+select entry_id, filename, url
+from entry_files
+order by entry_id, filename
+`)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Query for entry files failed: %v\n", err)
+		return files, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entryID string
+		var file EntryFile
+
+		err = rows.Scan(&entryID, &file.Filename, &file.URL)
+		if err != nil {
+			return files, err
+		}
+		files[entryID] = append(files[entryID], file)
+	}
+
+	return files, err
+}
+
 func (db *YPSDatabase) AddEntryFile(entry, filename, url string) (err error) {
 	_, err = db.pool.Exec(context.Background(), `
 insert into entry_files (entry_id, filename, url)
@@ -588,22 +1077,261 @@ where entry_id = $1 and filename = $2
 	return err
 }
 
-func (db *YPSDatabase) Search(params SearchRequest) (values SearchResponse, err error) {
-	values.Entries = []SearchEntry{}
-	values.Filters = []SearchFilter{}
-
-	var assembledParams []any
-	newParamNumber := 1
-	var whereClauses []string
+// GetEntryFileCounts returns how many files each entry with any files has.
+func (db *YPSDatabase) GetEntryFileCounts() (counts map[string]int, err error) {
+	counts = make(map[string]int)
 
-	rankQuery := `1`
-	if strings.TrimSpace(params.Query) != "" {
-		queryColumn := `alltextsearch_index_col`
-		if params.SearchContext == "title" {
-			queryColumn = `titlesearch_index_col`
-		} else if params.SearchContext == "abstract" {
-			queryColumn = `abstractsearch_index_col`
-		}
+	rows, err := db.pool.Query(context.Background(), `
+select entry_id, count(*) from entry_files group by entry_id
+`)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Entry file counts query failed: %v\n", err)
+		return counts, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var count int
+		err = rows.Scan(&id, &count)
+		if err != nil {
+			return counts, err
+		}
+		counts[id] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// MarkEntryManuallyEdited notes that the entry was changed outside of a spreadsheet import.
+func (db *YPSDatabase) MarkEntryManuallyEdited(id string) (err error) {
+	_, err = db.pool.Exec(context.Background(), `
+update entries set manually_edited_at=now() where id=$1
+`, id)
+	return err
+}
+
+// DeleteEntry removes the entry, along with any links to it from other
+// entries, and leaves the given tombstone in its place.
+func (db *YPSDatabase) DeleteEntry(tombstone EntryTombstone, change EntryChange) (err error) {
+	tx, err := db.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	err = setEntryChange(tx, change)
+	if err != nil {
+		return err
+	}
+
+	err = deleteEntryInTx(tx, tombstone)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+func deleteEntryInTx(tx pgx.Tx, tombstone EntryTombstone) (err error) {
+	id := tombstone.ID
+
+	// old IDs that led here lead to the replacement instead
+	if tombstone.ReplacedBy != "" {
+		_, err = tx.Exec(context.Background(), `
+update entry_id_aliases set entry_id=$2 where entry_id=$1
+`, id, tombstone.ReplacedBy)
+		if err != nil {
+			return err
+		}
+	}
+
+	tag, err := tx.Exec(context.Background(), `
+delete from entries where id=$1
+`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+
+	_, err = tx.Exec(context.Background(), `
+update entries
+set
+	alternates=array_remove(alternates, $1),
+	related=array_remove(related, $1)
+where $1 = ANY(alternates) or $1 = ANY(related)
+`, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(context.Background(), `
+delete from entry_relations where related_id=$1
+`, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(context.Background(), `
+delete from entry_files where entry_id=$1
+`, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(context.Background(), `
+insert into entry_tombstones (id, reason, replaced_by)
+values ($1, $2, nullif($3, ''))
+on conflict (id)
+do update
+set
+	deleted_at=now(),
+	reason=excluded.reason,
+	replaced_by=excluded.replaced_by
+`, id, tombstone.Reason, tombstone.ReplacedBy)
+	return err
+}
+
+// MergeEntries folds the remove entry into the keep one. keep picks up its
+// files, alternates, related entries and typed relations, links to remove
+// from other entries point at keep instead, and remove's ID becomes an alias
+// of keep. Files keep their existing URLs.
+func (db *YPSDatabase) MergeEntries(keep, remove string, change EntryChange) (err error) {
+	tx, err := db.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	err = setEntryChange(tx, change)
+	if err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(context.Background(), `
+update entries k
+set
+	alternates=array(
+		select value from unnest(k.alternates || r.alternates) with ordinality u(value, n)
+		where value <> k.id and value <> r.id
+		group by value
+		order by min(n)
+	),
+	related=array(
+		select value from unnest(k.related || r.related) with ordinality u(value, n)
+		where value <> k.id and value <> r.id
+		group by value
+		order by min(n)
+	),
+	manually_edited_at=now()
+from entries r
+where k.id=$1 and r.id=$2
+`, keep, remove)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+
+	_, err = tx.Exec(context.Background(), `
+update entries
+set
+	alternates=array(
+		select value from (
+			select case when value = $2 then $1 else value end as value, n
+			from unnest(entries.alternates) with ordinality u(value, n)
+		) replaced
+		where value <> entries.id
+		group by value
+		order by min(n)
+	),
+	related=array(
+		select value from (
+			select case when value = $2 then $1 else value end as value, n
+			from unnest(entries.related) with ordinality u(value, n)
+		) replaced
+		where value <> entries.id
+		group by value
+		order by min(n)
+	)
+where ($2 = ANY(alternates) or $2 = ANY(related)) and id <> $2
+`, keep, remove)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(context.Background(), `
+insert into entry_relations (entry_id, related_id, relation_type)
+select $1, related_id, relation_type from entry_relations where entry_id=$2 and related_id <> $1
+union
+select entry_id, $1, relation_type from entry_relations where related_id=$2 and entry_id <> $1 and entry_id <> $2
+on conflict do nothing
+`, keep, remove)
+	if err != nil {
+		return err
+	}
+
+	// keep's own files win when names clash
+	_, err = tx.Exec(context.Background(), `
+insert into entry_files (entry_id, filename, url)
+select $1, filename, url from entry_files where entry_id=$2
+on conflict (entry_id, filename) do nothing
+`, keep, remove)
+	if err != nil {
+		return err
+	}
+
+	err = deleteEntryInTx(tx, EntryTombstone{
+		ID:         remove,
+		Reason:     "Merged into " + keep,
+		ReplacedBy: keep,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(context.Background(), `
+insert into entry_id_aliases (alias, entry_id)
+values ($1, $2)
+on conflict (alias)
+do update
+set
+	entry_id=excluded.entry_id
+`, remove, keep)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+func (db *YPSDatabase) Search(params SearchRequest, includeNonPublic bool) (values SearchResponse, err error) {
+	values.Entries = []SearchEntry{}
+	values.Filters = []SearchFilter{}
+
+	var assembledParams []any
+	newParamNumber := 1
+	var whereClauses []string
+
+	visibleFilter := visibleEntriesFilter(includeNonPublic)
+	if !includeNonPublic {
+		whereClauses = append(whereClauses, visibleFilter)
+	}
+
+	rankQuery := `1`
+	if strings.TrimSpace(params.Query) != "" {
+		queryColumn := `alltextsearch_index_col`
+		if params.SearchContext == "title" {
+			queryColumn = `titlesearch_index_col`
+		} else if params.SearchContext == "abstract" {
+			queryColumn = `abstractsearch_index_col`
+		} else if params.SearchContext == "notes" && includeNonPublic {
+			// curator notes are private, so only admins search them
+			queryColumn = `to_tsvector('english', coalesce(notes, ''))`
+		}
 
 		rankQuery = fmt.Sprintf(`ts_rank_cd(%s, websearch_to_tsquery('english', $%d))`, queryColumn, newParamNumber)
 		whereClauses = append(whereClauses, fmt.Sprintf(`%s @@ websearch_to_tsquery('english', $%d)`, queryColumn, newParamNumber))
@@ -624,7 +1352,7 @@ func (db *YPSDatabase) Search(params SearchRequest) (values SearchResponse, err
 		newParamNumber += 1
 	}
 	if params.FilterKey == "year" {
-		whereClauses = append(whereClauses, fmt.Sprintf(`date_part('year', start_date) = $%d`, newParamNumber))
+		whereClauses = append(whereClauses, fmt.Sprintf(`date_precision <> 'unknown' AND $%d BETWEEN date_part('year', start_date) AND date_part('year', end_date)`, newParamNumber))
 		assembledParams = append(assembledParams, params.FilterValue)
 		newParamNumber += 1
 	}
@@ -633,11 +1361,32 @@ func (db *YPSDatabase) Search(params SearchRequest) (values SearchResponse, err
 		assembledParams = append(assembledParams, params.FilterValue)
 		newParamNumber += 1
 	}
-	if params.FilterKey == "keyword" {
+	if params.FilterKey == "keyword" && params.IncludeNarrower {
+		whereClauses = append(whereClauses, fmt.Sprintf(`($%d = ANY(keywords) OR keywords && array(
+	with recursive narrower as (
+		select id, name from keyword_terms where name = $%d
+		union
+		select keyword_terms.id, keyword_terms.name from keyword_terms join narrower on keyword_terms.broader_id = narrower.id
+	)
+	select name from narrower
+))`, newParamNumber, newParamNumber))
+		assembledParams = append(assembledParams, params.FilterValue)
+		newParamNumber += 1
+	} else if params.FilterKey == "keyword" {
 		whereClauses = append(whereClauses, fmt.Sprintf(`$%d = ANY(keywords)`, newParamNumber))
 		assembledParams = append(assembledParams, params.FilterValue)
 		newParamNumber += 1
 	}
+	if params.FilterKey == "author" {
+		whereClauses = append(whereClauses, fmt.Sprintf(`$%d = ANY(authors)`, newParamNumber))
+		assembledParams = append(assembledParams, params.FilterValue)
+		newParamNumber += 1
+	}
+	if params.FilterKey == "org" {
+		whereClauses = append(whereClauses, fmt.Sprintf(`$%d = ANY(orgs)`, newParamNumber))
+		assembledParams = append(assembledParams, params.FilterValue)
+		newParamNumber += 1
+	}
 	if params.FilterKey == "region" {
 		whereClauses = append(whereClauses, fmt.Sprintf(`$%d = ANY(regions)`, newParamNumber))
 		assembledParams = append(assembledParams, params.FilterValue)
@@ -746,7 +1495,7 @@ ORDER BY CASE
 
 	sortClause := `rank desc, start_date desc, id asc`
 	if params.Sort == "dateasc" {
-		sortClause = `start_date asc, id asc`
+		sortClause = `date_precision = 'unknown' asc, start_date asc, id asc`
 	} else if params.Sort == "datedesc" {
 		sortClause = `start_date desc, id desc`
 	} else if params.Sort == "abc" {
@@ -754,13 +1503,13 @@ ORDER BY CASE
 	}
 
 	assembledSearchQuery := fmt.Sprintf(`
-SELECT id, title, authors, start_date, end_date, entry_type, entry_language, array(select entry_language from entries e where e.id=entries.id or entries.id=ANY(alternates)) as languages, regions, %s AS rank
+SELECT id, title, authors, authors_et_al, start_date, end_date, date_precision, entry_type, entry_language, array(select entry_language from entries e where (e.id=entries.id or entries.id=ANY(alternates)) and %s) as languages, regions, %s AS rank
 FROM entries
 %s
 ORDER BY %s
 LIMIT %d
 OFFSET %d
-`, rankQuery, assembledWhereClause, sortClause, DefaultEntriesPerPage, startEntry)
+`, visibleFilter, rankQuery, assembledWhereClause, sortClause, DefaultEntriesPerPage, startEntry)
 
 	fmt.Println("SEARCH:", []any{assembledSearchQuery, len(assembledParams), assembledParams})
 
@@ -776,10 +1525,11 @@ OFFSET %d
 
 		var rank float32
 		var langCodes []string
-		err = rows.Scan(&e.ID, &e.Title, &e.Authors, &e.StartDate, &e.EndDate, &e.DocumentType, &e.Language, &langCodes, &e.Regions, &rank)
+		err = rows.Scan(&e.ID, &e.Title, &e.Authors, &e.AuthorsEtAl, &e.StartDate, &e.EndDate, &e.DatePrecision, &e.DocumentType, &e.Language, &langCodes, &e.Regions, &rank)
 		if err != nil {
 			return values, err
 		}
+		e.DateDisplay = FormatEntryDate(e.StartDate, e.EndDate, e.DatePrecision)
 		e.Language = ypsl.GetName(e.Language)
 		for _, lc := range langCodes {
 			e.AvailableLanguages = append(e.AvailableLanguages, ypsl.GetName(lc))
@@ -793,6 +1543,1034 @@ OFFSET %d
 	return values, nil
 }
 
+// replaceEntryArrayValues replaces oldValues with newValue in the given array
+// column of every entry. It keeps the order values were listed in, and drops
+// any that now end up doubled.
+func replaceEntryArrayValues(tx pgx.Tx, column string, newValue string, oldValues []string) error {
+	if column != "orgs" && column != "keywords" {
+		return fmt.Errorf("cannot replace values in column [%s]", column)
+	}
+
+	_, err := tx.Exec(context.Background(), fmt.Sprintf(`
+update entries
+set %s = array(
+	select value from (
+		select case when value = ANY($2) then $1 else value end as value, n
+		from unnest(entries.%s) with ordinality u(value, n)
+	) replaced
+	group by value
+	order by min(n)
+)
+where %s && $2
+`, column, column, column), newValue, oldValues)
+	return err
+}
+
+// similar entries
+//
+
+// GetSimilarEntries ranks other entries by how much they have in common with
+// the given one, leaving out its alternate languages. The text and title
+// scores are 0 to 1, and shared tags add a fixed amount each.
+func (db *YPSDatabase) GetSimilarEntries(id string, limit int, includeNonPublic bool) (similar []SimilarEntry, err error) {
+	similar = []SimilarEntry{}
+
+	// self doesn't have visibility columns, so the filter only applies to e
+	rows, err := db.pool.Query(context.Background(), fmt.Sprintf(`
+with self as (
+	select id, title, alternates, keywords, regions, orgs, entry_type,
+		(select string_agg(quote_literal(lexeme), ' | ') from unnest(tsvector_to_array(alltextsearch_index_col)) lexeme)::tsquery as query
+	from entries
+	where id=$1
+), scored as (
+	select e.id, e.title, e.entry_type, e.start_date, e.end_date, e.date_precision,
+		coalesce(ts_rank(e.alltextsearch_index_col, self.query, 1), 0) * 2
+		+ similarity(e.title, self.title) * 2
+		+ cardinality(array(select unnest(e.keywords) intersect select unnest(self.keywords))) * 0.3
+		+ cardinality(array(select unnest(e.regions) intersect select unnest(self.regions))) * 0.1
+		+ cardinality(array(select unnest(e.orgs) intersect select unnest(self.orgs))) * 0.2
+		+ (case when e.entry_type <> '' and e.entry_type = self.entry_type then 0.2 else 0 end) as score
+	from entries e, self
+	where e.id <> self.id and not (e.id = any(self.alternates)) and not (self.id = any(e.alternates)) and %s
+)
+select id, title, entry_type, start_date, end_date, date_precision, score
+from scored
+where score >= 0.3
+order by score desc, id asc
+limit $2
+`, visibleEntriesFilter(includeNonPublic)), id, limit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Similar entries query failed: %v\n", err)
+		return similar, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry SimilarEntry
+		var startDate, endDate time.Time
+		var datePrecision DatePrecision
+		err = rows.Scan(&entry.ID, &entry.Title, &entry.DocType, &startDate, &endDate, &datePrecision, &entry.Score)
+		if err != nil {
+			return similar, err
+		}
+		entry.DateDisplay = FormatEntryDate(startDate, endDate, datePrecision)
+		similar = append(similar, entry)
+	}
+
+	return similar, rows.Err()
+}
+
+// tombstones and aliases
+//
+
+func (db *YPSDatabase) GetAllEntryAliases() (aliases map[string][]string, err error) {
+	aliases = make(map[string][]string)
+
+	rows, err := db.pool.Query(context.Background(), `
+select entry_id, alias
+from entry_id_aliases
+order by alias
+`)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Entry aliases query failed: %v\n", err)
+		return aliases, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entryID, alias string
+		err = rows.Scan(&entryID, &alias)
+		if err != nil {
+			return aliases, err
+		}
+		aliases[entryID] = append(aliases[entryID], alias)
+	}
+
+	return aliases, err
+}
+
+// ResolveMissingEntry works out what happened to an entry ID that doesn't
+// exist. If it's an alias, redirectTo is the entry it leads to. Otherwise
+// tombstone is set if the entry was deleted. Both are empty if it never existed.
+func (db *YPSDatabase) ResolveMissingEntry(id string) (redirectTo string, tombstone *EntryTombstone, err error) {
+	err = db.pool.QueryRow(context.Background(), `
+select entry_id from entry_id_aliases where alias=$1
+`, id).Scan(&redirectTo)
+	if err == nil {
+		return redirectTo, nil, nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return "", nil, err
+	}
+
+	var t EntryTombstone
+	var replacedBy *string
+	err = db.pool.QueryRow(context.Background(), `
+select id, deleted_at, reason, replaced_by from entry_tombstones where id=$1
+`, id).Scan(&t.ID, &t.DeletedAt, &t.Reason, &replacedBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil, nil
+	} else if err != nil {
+		return "", nil, err
+	}
+	if replacedBy != nil {
+		t.ReplacedBy = *replacedBy
+	}
+
+	return "", &t, nil
+}
+
+// revisions
+//
+
+// setEntryChange notes who's making the changes to entries in this
+// transaction, so their revisions can be attributed.
+func setEntryChange(tx pgx.Tx, change EntryChange) error {
+	_, err := tx.Exec(context.Background(), `
+select set_config('yps.change_source', $1, true), set_config('yps.change_actor', $2, true)
+`, change.Source, change.Actor)
+	return err
+}
+
+func (db *YPSDatabase) GetEntryRevisions(entryID string) (revisions []EntryRevision, err error) {
+	revisions = []EntryRevision{}
+
+	rows, err := db.pool.Query(context.Background(), `
+select id, entry_id, changed_at, action, source, actor, old_values, new_values
+from entry_revisions
+where entry_id=$1
+order by id desc
+`, entryID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Getting entry revisions failed: %v\n", err)
+		return revisions, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var revision EntryRevision
+		var oldValues, newValues map[string]any
+		err = rows.Scan(&revision.ID, &revision.EntryID, &revision.ChangedAt, &revision.Action, &revision.Source, &revision.Actor, &oldValues, &newValues)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Getting entry revisions failed: %v\n", err)
+			return revisions, err
+		}
+		revision.Changes = diffEntryRevision(oldValues, newValues)
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, nil
+}
+
+// the entries columns that a revision restores
+const revisionEntryColumns = `id, url, entry_type, entry_language, regions, start_date, end_date, date_precision, alternates, related, title, authors, authors_et_al, abstract, keywords, orgs, org_doc_id, org_type, youth_led, youth_led_distilled, notes, visibility, embargo_until`
+
+// RestoreEntryRevision puts the entry back to how it was straight after the
+// given revision, or just before it for deletions. Typed relations aren't part
+// of the entry's row, so they're left as they are.
+func (db *YPSDatabase) RestoreEntryRevision(entryID string, revisionID int, change EntryChange) (err error) {
+	tx, err := db.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	err = setEntryChange(tx, change)
+	if err != nil {
+		return err
+	}
+
+	var values map[string]any
+	err = tx.QueryRow(context.Background(), `
+select case when action='delete' then old_values else new_values end
+from entry_revisions
+where id=$1 and entry_id=$2
+`, revisionID, entryID).Scan(&values)
+	if err != nil {
+		return err
+	}
+
+	if values == nil {
+		return fmt.Errorf("revision %d has nothing to restore", revisionID)
+	}
+
+	// revisions from before a column existed don't have it, so those columns
+	// keep their current value, or get their default when restoring a deletion
+	var columns, updates []string
+	for _, column := range strings.Split(revisionEntryColumns, ", ") {
+		if _, exists := values[column]; !exists {
+			continue
+		}
+		columns = append(columns, column)
+		if column != "id" {
+			updates = append(updates, fmt.Sprintf("%s=excluded.%s", column, column))
+		}
+	}
+	values["id"] = entryID
+	if !slices.Contains(columns, "id") {
+		columns = append(columns, "id")
+	}
+	_, err = tx.Exec(context.Background(), fmt.Sprintf(`
+insert into entries (%s, manually_edited_at)
+select %s, now()
+from jsonb_populate_record(null::entries, $1::jsonb)
+on conflict (id)
+do update
+set %s
+`, strings.Join(columns, ", "), strings.Join(columns, ", "), strings.Join(append(updates, "manually_edited_at=excluded.manually_edited_at"), ", ")), values)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Restoring entry revision failed: %v\n", err)
+		return err
+	}
+
+	_, err = tx.Exec(context.Background(), `
+delete from entry_tombstones where id=$1
+`, entryID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+// organisations
+//
+
+func (db *YPSDatabase) GetOrgs(includeNonPublic bool) (orgs []Organisation, err error) {
+	orgs = []Organisation{}
+
+	rows, err := db.pool.Query(context.Background(), fmt.Sprintf(`
+select id, name, aliases, org_type, country, website,
+	(select count(*) from entries where organisations.name = ANY(entries.orgs) and %s)
+from organisations
+order by lower(name) asc
+`, visibleEntriesFilter(includeNonPublic)))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Organisations query failed: %v\n", err)
+		return orgs, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var org Organisation
+		err = rows.Scan(&org.ID, &org.Name, &org.Aliases, &org.OrgType, &org.Country, &org.Website, &org.Entries)
+		if err != nil {
+			return orgs, err
+		}
+		orgs = append(orgs, org)
+	}
+
+	return orgs, err
+}
+
+func (db *YPSDatabase) GetOrg(id int, includeNonPublic bool) (org Organisation, entries []SearchEntry, err error) {
+	entries = []SearchEntry{}
+
+	err = db.pool.QueryRow(context.Background(), `
+select id, name, aliases, org_type, country, website
+from organisations
+where id=$1
+`, id).Scan(&org.ID, &org.Name, &org.Aliases, &org.OrgType, &org.Country, &org.Website)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "QueryRow for organisation failed: %v\n", err)
+		return org, entries, err
+	}
+
+	rows, err := db.pool.Query(context.Background(), fmt.Sprintf(`
+select id, title, authors, authors_et_al, start_date, end_date, date_precision, entry_type, entry_language, regions
+from entries
+where $1 = ANY(orgs) and %s
+order by date_precision = 'unknown' asc, start_date desc, id asc
+`, visibleEntriesFilter(includeNonPublic)), org.Name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Organisation entries query failed: %v\n", err)
+		return org, entries, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e SearchEntry
+		err = rows.Scan(&e.ID, &e.Title, &e.Authors, &e.AuthorsEtAl, &e.StartDate, &e.EndDate, &e.DatePrecision, &e.DocumentType, &e.Language, &e.Regions)
+		if err != nil {
+			return org, entries, err
+		}
+		e.DateDisplay = FormatEntryDate(e.StartDate, e.EndDate, e.DatePrecision)
+		e.Language = ypsl.GetName(e.Language)
+		entries = append(entries, e)
+	}
+	org.Entries = len(entries)
+
+	return org, entries, err
+}
+
+// GetOrgNameLookup maps the normalised name and aliases of every organisation
+// to its canonical name.
+func (db *YPSDatabase) GetOrgNameLookup() (lookup map[string]string, err error) {
+	orgs, err := db.GetOrgs(true)
+	if err != nil {
+		return nil, err
+	}
+
+	lookup = make(map[string]string)
+	for _, org := range orgs {
+		lookup[NormaliseOrgName(org.Name)] = org.Name
+		for _, alias := range org.Aliases {
+			lookup[NormaliseOrgName(alias)] = org.Name
+		}
+	}
+
+	return lookup, nil
+}
+
+// SaveOrg creates the organisation if its ID is 0, otherwise updates it. Entries
+// that list the organisation under its old name or any of its aliases are
+// updated to use its canonical name.
+func (db *YPSDatabase) SaveOrg(org Organisation, change EntryChange) (id int, err error) {
+	if org.Aliases == nil {
+		org.Aliases = []string{}
+	}
+
+	tx, err := db.pool.Begin(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(context.Background())
+
+	err = setEntryChange(tx, change)
+	if err != nil {
+		return 0, err
+	}
+
+	replacedNames := slices.Clone(org.Aliases)
+	if org.ID == 0 {
+		err = tx.QueryRow(context.Background(), `
+insert into organisations (name, aliases, org_type, country, website)
+values ($1, $2, $3, $4, $5)
+returning id
+`, org.Name, org.Aliases, org.OrgType, org.Country, org.Website).Scan(&id)
+	} else {
+		var oldName string
+		err = tx.QueryRow(context.Background(), `
+select name from organisations where id=$1
+`, org.ID).Scan(&oldName)
+		if err != nil {
+			return 0, err
+		}
+		replacedNames = append(replacedNames, oldName)
+
+		id = org.ID
+		_, err = tx.Exec(context.Background(), `
+update organisations
+set
+	name=$2,
+	aliases=$3,
+	org_type=$4,
+	country=$5,
+	website=$6
+where id=$1
+`, org.ID, org.Name, org.Aliases, org.OrgType, org.Country, org.Website)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Saving organisation failed: %v\n", err)
+		return 0, err
+	}
+
+	err = replaceEntryArrayValues(tx, "orgs", org.Name, replacedNames)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Renaming organisation on entries failed: %v\n", err)
+		return 0, err
+	}
+
+	return id, tx.Commit(context.Background())
+}
+
+func (db *YPSDatabase) DeleteOrg(id int) (err error) {
+	_, err = db.pool.Exec(context.Background(), `
+delete from organisations where id=$1
+`, id)
+	return err
+}
+
+// keywords
+//
+
+func (db *YPSDatabase) GetKeywordTerms(includeNonPublic bool) (terms []KeywordTerm, err error) {
+	terms = []KeywordTerm{}
+
+	rows, err := db.pool.Query(context.Background(), fmt.Sprintf(`
+select id, name, description, synonyms, broader_id,
+	(select count(*) from entries where keyword_terms.name = ANY(entries.keywords) and %s)
+from keyword_terms
+order by lower(name) asc
+`, visibleEntriesFilter(includeNonPublic)))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Keyword terms query failed: %v\n", err)
+		return terms, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var term KeywordTerm
+		err = rows.Scan(&term.ID, &term.Name, &term.Description, &term.Synonyms, &term.BroaderID, &term.Entries)
+		if err != nil {
+			return terms, err
+		}
+		terms = append(terms, term)
+	}
+
+	return terms, err
+}
+
+// GetKeywordEntryIDs returns the IDs of the entries tagged with each keyword.
+func (db *YPSDatabase) GetKeywordEntryIDs(includeNonPublic bool) (entryIDs map[string][]string, err error) {
+	entryIDs = make(map[string][]string)
+
+	rows, err := db.pool.Query(context.Background(), fmt.Sprintf(`
+select keyword, array_agg(id)
+from entries cross join lateral
+  unnest(entries.keywords) keyword
+where %s
+group by keyword
+`, visibleEntriesFilter(includeNonPublic)))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Keyword entries query failed: %v\n", err)
+		return entryIDs, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var keyword string
+		var ids []string
+		err = rows.Scan(&keyword, &ids)
+		if err != nil {
+			return entryIDs, err
+		}
+		entryIDs[keyword] = ids
+	}
+
+	return entryIDs, err
+}
+
+// GetKeywordTermLookup maps the normalised name and synonyms of every keyword
+// term to its name.
+func (db *YPSDatabase) GetKeywordTermLookup() (lookup map[string]string, err error) {
+	terms, err := db.GetKeywordTerms(true)
+	if err != nil {
+		return nil, err
+	}
+
+	lookup = make(map[string]string)
+	for _, term := range terms {
+		lookup[NormaliseVocabularyValue(term.Name)] = term.Name
+		for _, synonym := range term.Synonyms {
+			lookup[NormaliseVocabularyValue(synonym)] = term.Name
+		}
+	}
+
+	return lookup, nil
+}
+
+// SaveKeywordTerm creates the term if its ID is 0, otherwise updates it.
+// Entries tagged with the term's old name or any of its synonyms are updated
+// to use its name.
+func (db *YPSDatabase) SaveKeywordTerm(term KeywordTerm, change EntryChange) (id int, err error) {
+	if term.Synonyms == nil {
+		term.Synonyms = []string{}
+	}
+
+	tx, err := db.pool.Begin(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(context.Background())
+
+	err = setEntryChange(tx, change)
+	if err != nil {
+		return 0, err
+	}
+
+	replacedNames := slices.Clone(term.Synonyms)
+	if term.ID == 0 {
+		err = tx.QueryRow(context.Background(), `
+insert into keyword_terms (name, description, synonyms, broader_id)
+values ($1, $2, $3, $4)
+returning id
+`, term.Name, term.Description, term.Synonyms, term.BroaderID).Scan(&id)
+	} else {
+		var oldName string
+		err = tx.QueryRow(context.Background(), `
+select name from keyword_terms where id=$1
+`, term.ID).Scan(&oldName)
+		if err != nil {
+			return 0, err
+		}
+		replacedNames = append(replacedNames, oldName)
+
+		id = term.ID
+		_, err = tx.Exec(context.Background(), `
+update keyword_terms
+set
+	name=$2,
+	description=$3,
+	synonyms=$4,
+	broader_id=$5
+where id=$1
+`, term.ID, term.Name, term.Description, term.Synonyms, term.BroaderID)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Saving keyword term failed: %v\n", err)
+		return 0, err
+	}
+
+	err = replaceEntryArrayValues(tx, "keywords", term.Name, replacedNames)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Renaming keyword on entries failed: %v\n", err)
+		return 0, err
+	}
+
+	return id, tx.Commit(context.Background())
+}
+
+// MergeKeywordTerms folds the source term into the target one. The source's
+// name and synonyms become synonyms of the target, its narrower terms move
+// under the target, and entries tagged with it are retagged with the target.
+func (db *YPSDatabase) MergeKeywordTerms(sourceID, targetID int, change EntryChange) (err error) {
+	tx, err := db.pool.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	err = setEntryChange(tx, change)
+	if err != nil {
+		return err
+	}
+
+	var source, target KeywordTerm
+	err = tx.QueryRow(context.Background(), `
+select id, name, synonyms, broader_id from keyword_terms where id=$1
+`, sourceID).Scan(&source.ID, &source.Name, &source.Synonyms, &source.BroaderID)
+	if err != nil {
+		return err
+	}
+	err = tx.QueryRow(context.Background(), `
+select id, name, synonyms, broader_id from keyword_terms where id=$1
+`, targetID).Scan(&target.ID, &target.Name, &target.Synonyms, &target.BroaderID)
+	if err != nil {
+		return err
+	}
+
+	replacedNames := append([]string{source.Name}, source.Synonyms...)
+	for _, name := range replacedNames {
+		if !slices.Contains(target.Synonyms, name) {
+			target.Synonyms = append(target.Synonyms, name)
+		}
+	}
+
+	// the target can't end up underneath itself
+	broaderOf := make(map[int]*int)
+	rows, err := tx.Query(context.Background(), `
+select id, broader_id from keyword_terms
+`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id int
+		var broaderID *int
+		err = rows.Scan(&id, &broaderID)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		broaderOf[id] = broaderID
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return rows.Err()
+	}
+	target.BroaderID, err = mergedBroaderID(source, target, broaderOf)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(context.Background(), `
+update keyword_terms set broader_id=$2 where broader_id=$1 and id<>$2
+`, source.ID, target.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(context.Background(), `
+delete from keyword_terms where id=$1
+`, source.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(context.Background(), `
+update keyword_terms set synonyms=$2, broader_id=$3 where id=$1
+`, target.ID, target.Synonyms, target.BroaderID)
+	if err != nil {
+		return err
+	}
+
+	err = replaceEntryArrayValues(tx, "keywords", target.Name, replacedNames)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Merging keyword on entries failed: %v\n", err)
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+// DeleteKeywordTerm removes the term from the taxonomy. Entries keep the
+// keyword, and its narrower terms become top-level terms.
+func (db *YPSDatabase) DeleteKeywordTerm(id int) (err error) {
+	_, err = db.pool.Exec(context.Background(), `
+delete from keyword_terms where id=$1
+`, id)
+	return err
+}
+
+// relations
+//
+
+func (db *YPSDatabase) GetRelationTypes() (relationTypes []RelationType, err error) {
+	relationTypes = []RelationType{}
+
+	rows, err := db.pool.Query(context.Background(), `
+select name, label, inverse_label, symmetric
+from relation_types
+order by name asc
+`)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Relation types query failed: %v\n", err)
+		return relationTypes, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var relationType RelationType
+		err = rows.Scan(&relationType.Name, &relationType.Label, &relationType.InverseLabel, &relationType.Symmetric)
+		if err != nil {
+			return relationTypes, err
+		}
+		relationTypes = append(relationTypes, relationType)
+	}
+
+	return relationTypes, err
+}
+
+func (db *YPSDatabase) SaveRelationType(relationType RelationType) (err error) {
+	_, err = db.pool.Exec(context.Background(), `
+insert into relation_types (name, label, inverse_label, symmetric)
+values ($1, $2, $3, $4)
+on conflict (name)
+do update
+set
+	label=excluded.label,
+	inverse_label=excluded.inverse_label,
+	symmetric=excluded.symmetric
+`, relationType.Name, relationType.Label, relationType.InverseLabel, relationType.Symmetric)
+	return err
+}
+
+func (db *YPSDatabase) DeleteRelationType(name string) (err error) {
+	_, err = db.pool.Exec(context.Background(), `
+delete from relation_types where name=$1
+`, name)
+	return err
+}
+
+// GetAllEntryRelations returns the typed relations of every entry that has any.
+func (db *YPSDatabase) GetAllEntryRelations() (relations map[string][]EntryRelation, err error) {
+	relations = make(map[string][]EntryRelation)
+
+	rows, err := db.pool.Query(context.Background(), `
+select entry_id, relation_type, related_id
+from entry_relations
+`)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Entry relations query failed: %v\n", err)
+		return relations, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entryID string
+		var relation EntryRelation
+		err = rows.Scan(&entryID, &relation.Type, &relation.ID)
+		if err != nil {
+			return relations, err
+		}
+		relations[entryID] = append(relations[entryID], relation)
+	}
+	for _, entryRelations := range relations {
+		sortRelations(entryRelations)
+	}
+
+	return relations, err
+}
+
+// vocabulary
+//
+
+func (db *YPSDatabase) GetVocabularyRules() (rules []VocabularyRule, err error) {
+	rules = []VocabularyRule{}
+
+	rows, err := db.pool.Query(context.Background(), `
+select id, field, match_type, raw_value, canonical_value, priority
+from vocabulary_rules
+order by priority asc, id asc
+`)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Vocabulary rules query failed: %v\n", err)
+		return rules, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rule VocabularyRule
+		err = rows.Scan(&rule.ID, &rule.Field, &rule.MatchType, &rule.RawValue, &rule.CanonicalValue, &rule.Priority)
+		if err != nil {
+			return rules, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, err
+}
+
+// SaveVocabularyRule creates the rule if its ID is 0, otherwise updates it.
+func (db *YPSDatabase) SaveVocabularyRule(rule VocabularyRule) (id int, err error) {
+	if rule.ID == 0 {
+		err = db.pool.QueryRow(context.Background(), `
+insert into vocabulary_rules (field, match_type, raw_value, canonical_value, priority)
+values ($1, $2, $3, $4, $5)
+returning id
+`, rule.Field, rule.MatchType, rule.RawValue, rule.CanonicalValue, rule.Priority).Scan(&id)
+	} else {
+		err = db.pool.QueryRow(context.Background(), `
+update vocabulary_rules
+set
+	field=$2,
+	match_type=$3,
+	raw_value=$4,
+	canonical_value=$5,
+	priority=$6
+where id=$1
+returning id
+`, rule.ID, rule.Field, rule.MatchType, rule.RawValue, rule.CanonicalValue, rule.Priority).Scan(&id)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Saving vocabulary rule failed: %v\n", err)
+	}
+	return id, err
+}
+
+func (db *YPSDatabase) DeleteVocabularyRule(id int) (err error) {
+	_, err = db.pool.Exec(context.Background(), `
+delete from vocabulary_rules where id=$1
+`, id)
+	return err
+}
+
+// GetKnownVocabulary returns the values of each field that are already in use.
+func (db *YPSDatabase) GetKnownVocabulary() (known map[VocabularyField]map[string]bool, err error) {
+	known = make(map[VocabularyField]map[string]bool)
+
+	rows, err := db.pool.Query(context.Background(), `
+select 'entry_type', entry_type from entries where entry_type <> ''
+union
+select 'org_type', org_type from entries where org_type <> ''
+union
+select 'keyword', keyword from entries cross join lateral unnest(entries.keywords) keyword
+`)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Known vocabulary query failed: %v\n", err)
+		return known, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var field VocabularyField
+		var value string
+		err = rows.Scan(&field, &value)
+		if err != nil {
+			return known, err
+		}
+		if known[field] == nil {
+			known[field] = make(map[string]bool)
+		}
+		known[field][value] = true
+	}
+
+	return known, err
+}
+
+// submissions
+//
+
+const submissionColumns = `id, status, submitted_at, updated_at, submitter_name, submitter_email, submitter_ip, fields, files, coalesce(entry_id, ''), review_note`
+
+func scanSubmission(row pgx.Row) (sub Submission, err error) {
+	err = row.Scan(&sub.ID, &sub.Status, &sub.SubmittedAt, &sub.UpdatedAt, &sub.SubmitterName, &sub.SubmitterEmail, &sub.SubmitterIP, &sub.Fields, &sub.Files, &sub.EntryID, &sub.ReviewNote)
+	return sub, err
+}
+
+func (db *YPSDatabase) CreateSubmission(sub Submission, token string) (id int, err error) {
+	err = db.pool.QueryRow(context.Background(), `
+insert into submissions (token, submitter_name, submitter_email, submitter_ip, fields, files)
+values ($1, $2, $3, $4, $5, $6)
+returning id
+`, token, sub.SubmitterName, sub.SubmitterEmail, sub.SubmitterIP, sub.Fields, sub.Files).Scan(&id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Creating submission failed: %v\n", err)
+	}
+	return id, err
+}
+
+// CountRecentSubmissions returns how many submissions have come from the given IP since the given time.
+func (db *YPSDatabase) CountRecentSubmissions(ip string, since time.Time) (count int, err error) {
+	err = db.pool.QueryRow(context.Background(), `
+select count(*) from submissions where submitter_ip=$1 and submitted_at >= $2
+`, ip, since).Scan(&count)
+	return count, err
+}
+
+// GetSubmissions returns submissions with the given status, or every submission
+// if it's blank. Oldest are first, since they've been waiting longest.
+func (db *YPSDatabase) GetSubmissions(status SubmissionStatus) (submissions []Submission, err error) {
+	submissions = []Submission{}
+
+	rows, err := db.pool.Query(context.Background(), `
+select `+submissionColumns+`
+from submissions
+where $1 = '' or status = $1
+order by submitted_at asc, id asc
+`, status)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Submissions query failed: %v\n", err)
+		return submissions, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		sub, err := scanSubmission(rows)
+		if err != nil {
+			return submissions, err
+		}
+		submissions = append(submissions, sub)
+	}
+
+	return submissions, rows.Err()
+}
+
+func (db *YPSDatabase) GetSubmission(id int) (sub Submission, err error) {
+	return scanSubmission(db.pool.QueryRow(context.Background(), `
+select `+submissionColumns+`
+from submissions
+where id=$1
+`, id))
+}
+
+func (db *YPSDatabase) GetSubmissionByToken(token string) (sub Submission, err error) {
+	return scanSubmission(db.pool.QueryRow(context.Background(), `
+select `+submissionColumns+`
+from submissions
+where token=$1
+`, token))
+}
+
+// SetSubmissionFiles replaces the files of the submission.
+func (db *YPSDatabase) SetSubmissionFiles(id int, files []EntryFile) (err error) {
+	_, err = db.pool.Exec(context.Background(), `
+update submissions set files=$2, updated_at=now() where id=$1
+`, id, files)
+	return err
+}
+
+// UpdateSubmissionFields replaces the entry fields of a pending submission.
+// If the submission doesn't exist or has already been reviewed, err is sql.ErrNoRows.
+func (db *YPSDatabase) UpdateSubmissionFields(id int, fields EditEntryParams) (err error) {
+	tag, err := db.pool.Exec(context.Background(), `
+update submissions set fields=$2, updated_at=now() where id=$1 and status='pending'
+`, id, fields)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ReviewSubmission moves the submission from one status to another, e.g. to
+// reject a pending submission. If the submission doesn't exist or isn't in the
+// from status anymore, err is sql.ErrNoRows.
+func (db *YPSDatabase) ReviewSubmission(id int, from, to SubmissionStatus, entryID, note string) (err error) {
+	tag, err := db.pool.Exec(context.Background(), `
+update submissions
+set
+	status=$3,
+	entry_id=nullif($4, ''),
+	review_note=$5,
+	updated_at=now()
+where id=$1 and status=$2
+`, id, from, to, entryID, note)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (db *YPSDatabase) DeleteSubmission(id int) (err error) {
+	_, err = db.pool.Exec(context.Background(), `
+delete from submissions where id=$1
+`, id)
+	return err
+}
+
+// entry reports
+//
+
+func (db *YPSDatabase) CreateEntryReport(report EntryReport, ip string) (id int, err error) {
+	err = db.pool.QueryRow(context.Background(), `
+insert into entry_reports (entry_id, category, field, message, reporter_ip)
+values ($1, $2, $3, $4, $5)
+returning id
+`, report.EntryID, report.Category, report.Field, report.Message, ip).Scan(&id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Creating entry report failed: %v\n", err)
+	}
+	return id, err
+}
+
+// PublicEntryExists returns whether the entry exists and can be seen by anyone.
+func (db *YPSDatabase) PublicEntryExists(id string) (exists bool, err error) {
+	err = db.pool.QueryRow(context.Background(), fmt.Sprintf(`
+select exists(select from entries where id=$1 and %s)
+`, publicEntriesFilter), id).Scan(&exists)
+	return exists, err
+}
+
+// CountRecentEntryReports returns how many reports have come from the given IP since the given time.
+func (db *YPSDatabase) CountRecentEntryReports(ip string, since time.Time) (count int, err error) {
+	err = db.pool.QueryRow(context.Background(), `
+select count(*) from entry_reports where reporter_ip=$1 and reported_at >= $2
+`, ip, since).Scan(&count)
+	return count, err
+}
+
+// GetEntryReports returns reports with the given status and about the given
+// entry, if they aren't blank. Oldest are first, since they've been waiting longest.
+func (db *YPSDatabase) GetEntryReports(status EntryReportStatus, entryID string) (reports []EntryReport, err error) {
+	reports = []EntryReport{}
+
+	rows, err := db.pool.Query(context.Background(), `
+select r.id, r.entry_id, coalesce(e.title, ''), e.id is not null, r.category, r.field, r.message, r.status, r.reported_at, r.resolved_at, r.resolved_by, r.resolution_note
+from entry_reports r
+left join entries e on e.id = r.entry_id
+where ($1 = '' or r.status = $1) and ($2 = '' or r.entry_id = $2)
+order by r.reported_at asc, r.id asc
+`, status, entryID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Entry reports query failed: %v\n", err)
+		return reports, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var report EntryReport
+		err = rows.Scan(&report.ID, &report.EntryID, &report.EntryTitle, &report.EntryExists, &report.Category, &report.Field, &report.Message, &report.Status, &report.ReportedAt, &report.ResolvedAt, &report.ResolvedBy, &report.ResolutionNote)
+		if err != nil {
+			return reports, err
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, rows.Err()
+}
+
+// SetEntryReportStatus moves the report to the given status. Closing it notes
+// who closed it and when, and reopening it clears that.
+func (db *YPSDatabase) SetEntryReportStatus(id int, status EntryReportStatus, actor, note string) (report EntryReport, err error) {
+	err = db.pool.QueryRow(context.Background(), `
+update entry_reports
+set
+	status=$2,
+	resolved_at=case when $2 = 'open' then null else now() end,
+	resolved_by=case when $2 = 'open' then '' else $3 end,
+	resolution_note=$4
+where id=$1
+returning id, entry_id, category, field, message, status, reported_at, resolved_at, resolved_by, resolution_note
+`, id, status, actor, note).Scan(&report.ID, &report.EntryID, &report.Category, &report.Field, &report.Message, &report.Status, &report.ReportedAt, &report.ResolvedAt, &report.ResolvedBy, &report.ResolutionNote)
+	return report, err
+}
+
 // dynamic pages
 //
 
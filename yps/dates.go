@@ -0,0 +1,339 @@
+package yps
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type DatePrecision string
+
+const (
+	DatePrecisionUnknown DatePrecision = "unknown"
+	DatePrecisionYear    DatePrecision = "year"
+	DatePrecisionMonth   DatePrecision = "month"
+	DatePrecisionDay     DatePrecision = "day"
+)
+
+// EntryDate is a publication date, or range of dates. Start and End are the
+// first day of the first and last period covered (e.g. for a span of months,
+// End is the 1st of the last month), and are only as precise as Precision says.
+type EntryDate struct {
+	Start     time.Time
+	End       time.Time
+	Precision DatePrecision
+}
+
+var monthNameToNumber = map[string]int{
+	"january":   1,
+	"february":  2,
+	"march":     3,
+	"april":     4,
+	"may":       5,
+	"june":      6,
+	"july":      7,
+	"august":    8,
+	"september": 9,
+	"october":   10,
+	"november":  11,
+	"december":  12,
+}
+
+// seasons are for the northern hemisphere, and winter runs into the next year.
+var seasonToMonths = map[string][2]int{
+	"spring": {3, 5},
+	"summer": {6, 8},
+	"autumn": {9, 11},
+	"fall":   {9, 11},
+	"winter": {12, 14},
+}
+
+var (
+	isoDateRegex   = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})(?:[ T].*)?$`)
+	isoMonthRegex  = regexp.MustCompile(`^(\d{4})-(0?[1-9]|1[0-2])$`)
+	yearRegex      = regexp.MustCompile(`^(\d{4})$`)
+	yearSpanRegex  = regexp.MustCompile(`^(\d{4})\s*(?:-|–|—|/|to)\s*(\d{2}|\d{4})$`)
+	quarterRegex   = regexp.MustCompile(`^q([1-4])(?:\s+\d{4})?$`)
+	rangeSeparator = regexp.MustCompile(`\s*(?:-|–|—|\bto\b)\s*`)
+	ordinalSuffix  = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)$`)
+)
+
+func isEmptyDateValue(input string) bool {
+	switch strings.ToLower(strings.TrimSpace(input)) {
+	case "", "n/a", "na", "unknown", "nd", "n.d.", "0":
+		return true
+	}
+	return false
+}
+
+func makeDate(year, month, day int) (time.Time, error) {
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if month < 1 || month > 12 || t.Day() != day {
+		return t, fmt.Errorf("%d-%02d-%02d is not a real date", year, month, day)
+	}
+	return t, nil
+}
+
+// parseMonthAndDay reads values like 'March', 'Sept', '5 March', 'March 5th'.
+// day is 0 if it isn't given.
+func parseMonthAndDay(input string) (month, day int, err error) {
+	for _, token := range strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return r == ' ' || r == ',' || r == '.'
+	}) {
+		if matches := ordinalSuffix.FindStringSubmatch(token); matches != nil {
+			token = matches[1]
+		}
+
+		num, numErr := strconv.Atoi(token)
+		if numErr == nil && num > 1000 {
+			// the year's already in its own column
+			continue
+		} else if numErr == nil {
+			if day != 0 || num < 1 || num > 31 {
+				return 0, 0, fmt.Errorf("could not understand day in [%s]", input)
+			}
+			day = num
+			continue
+		}
+
+		tokenMonth := 0
+		for monthName, monthNumber := range monthNameToNumber {
+			if token == monthName || (len(token) >= 3 && strings.HasPrefix(monthName, token)) {
+				tokenMonth = monthNumber
+			}
+		}
+		if tokenMonth == 0 || month != 0 {
+			return 0, 0, fmt.Errorf("could not understand month in [%s]", input)
+		}
+		month = tokenMonth
+	}
+
+	if month == 0 && day == 0 {
+		return 0, 0, fmt.Errorf("could not find a month or day in [%s]", input)
+	}
+	return month, day, nil
+}
+
+// parseYears reads the Year column, which is either a year, a span of years,
+// or a full or partial ISO date.
+func parseYears(rawYear string) (startYear, endYear int, isoDate *EntryDate, err error) {
+	rawYear = strings.ToLower(strings.TrimSpace(rawYear))
+
+	if matches := isoDateRegex.FindStringSubmatch(rawYear); matches != nil {
+		year, _ := strconv.Atoi(matches[1])
+		month, _ := strconv.Atoi(matches[2])
+		day, _ := strconv.Atoi(matches[3])
+		t, err := makeDate(year, month, day)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		return year, year, &EntryDate{t, t, DatePrecisionDay}, nil
+	}
+
+	// 2019-21 is a span of years and 2019-05 is a month. ones that could be
+	// either, like 2011-12, are read as spans, while 2019-12 can only be a month
+	if matches := yearSpanRegex.FindStringSubmatch(rawYear); matches != nil && !strings.HasPrefix(matches[2], "0") {
+		startYear, endYear = parseYearSpan(matches)
+		if endYear >= startYear {
+			return startYear, endYear, nil, nil
+		} else if !isoMonthRegex.MatchString(rawYear) {
+			return 0, 0, nil, fmt.Errorf("year span [%s] ends before it starts", rawYear)
+		}
+	}
+
+	if matches := isoMonthRegex.FindStringSubmatch(rawYear); matches != nil {
+		year, _ := strconv.Atoi(matches[1])
+		month, _ := strconv.Atoi(matches[2])
+		t, err := makeDate(year, month, 1)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		return year, year, &EntryDate{t, t, DatePrecisionMonth}, nil
+	}
+
+	if matches := yearRegex.FindStringSubmatch(rawYear); matches != nil {
+		year, _ := strconv.Atoi(matches[1])
+		return year, year, nil, nil
+	}
+
+	return 0, 0, nil, fmt.Errorf("could not understand year [%s]", rawYear)
+}
+
+// parseYearSpan reads the years out of a yearSpanRegex match.
+func parseYearSpan(matches []string) (startYear, endYear int) {
+	startYear, _ = strconv.Atoi(matches[1])
+	endYear, _ = strconv.Atoi(matches[2])
+	if len(matches[2]) == 2 {
+		// 2019-21
+		endYear += startYear - startYear%100
+	}
+	return startYear, endYear
+}
+
+// ParseEntryDate works out the publication date from the Year and Day / Month
+// columns. If the year can be read but the day / month can't, a year-precision
+// date is returned along with the error.
+func ParseEntryDate(rawYear, rawDayMonth string) (date EntryDate, err error) {
+	date.Precision = DatePrecisionUnknown
+	if isEmptyDateValue(rawYear) {
+		return date, nil
+	}
+
+	startYear, endYear, isoDate, err := parseYears(rawYear)
+	if err != nil {
+		return date, err
+	}
+	if isoDate != nil {
+		return *isoDate, nil
+	}
+
+	date.Start, _ = makeDate(startYear, 1, 1)
+	date.End, _ = makeDate(endYear, 1, 1)
+	date.Precision = DatePrecisionYear
+
+	rawDayMonth = strings.ToLower(strings.TrimSpace(rawDayMonth))
+	if isEmptyDateValue(rawDayMonth) {
+		return date, nil
+	}
+
+	// an iso date in the day / month column, quirk of how dates get entered
+	if matches := isoDateRegex.FindStringSubmatch(rawDayMonth); matches != nil {
+		month, _ := strconv.Atoi(matches[2])
+		day, _ := strconv.Atoi(matches[3])
+		t, err := makeDate(startYear, month, day)
+		if err != nil {
+			return date, err
+		}
+		return EntryDate{t, t, DatePrecisionDay}, nil
+	}
+
+	// quarters and seasons
+	var startMonth, endMonth int
+	if matches := quarterRegex.FindStringSubmatch(rawDayMonth); matches != nil {
+		quarter, _ := strconv.Atoi(matches[1])
+		startMonth, endMonth = quarter*3-2, quarter*3
+	} else if months, exists := seasonToMonths[strings.Fields(rawDayMonth)[0]]; exists {
+		startMonth, endMonth = months[0], months[1]
+	}
+	if startMonth != 0 {
+		date.Start, _ = makeDate(startYear, startMonth, 1)
+		date.End, _ = makeDate(endYear+(endMonth-1)/12, (endMonth-1)%12+1, 1)
+		date.Precision = DatePrecisionMonth
+		return date, nil
+	}
+
+	// single dates and ranges of dates
+	parts := rangeSeparator.Split(rawDayMonth, -1)
+	if len(parts) > 2 {
+		return date, fmt.Errorf("could not understand day / month [%s]", rawDayMonth)
+	}
+
+	startMonth, startDay, err := parseMonthAndDay(parts[0])
+	if err != nil {
+		return date, err
+	}
+	endMonth, endDay := startMonth, startDay
+	if len(parts) == 2 {
+		endMonth, endDay, err = parseMonthAndDay(parts[1])
+		if err != nil {
+			return date, err
+		}
+	}
+
+	// '5 - 7 March'
+	if startMonth == 0 {
+		startMonth = endMonth
+	}
+	if endMonth == 0 {
+		endMonth = startMonth
+	}
+	if startMonth == 0 {
+		return date, fmt.Errorf("could not find a month in [%s]", rawDayMonth)
+	}
+
+	// 'December - February' in a single year runs into the next one
+	if startYear == endYear && (endMonth < startMonth || (endMonth == startMonth && endDay != 0 && endDay < startDay)) {
+		endYear += 1
+	}
+
+	precision := DatePrecisionDay
+	if startDay == 0 || endDay == 0 {
+		precision = DatePrecisionMonth
+		startDay, endDay = 1, 1
+	}
+
+	start, err := makeDate(startYear, startMonth, startDay)
+	if err != nil {
+		return date, err
+	}
+	end, err := makeDate(endYear, endMonth, endDay)
+	if err != nil {
+		return date, err
+	}
+
+	return EntryDate{start, end, precision}, nil
+}
+
+// FormatEntryDate returns a human-readable date, which is only as precise as the data we have.
+func FormatEntryDate(start, end time.Time, precision DatePrecision) string {
+	if end.Before(start) {
+		end = start
+	}
+
+	switch precision {
+	case DatePrecisionYear:
+		if start.Year() == end.Year() {
+			return start.Format("2006")
+		}
+		return fmt.Sprintf("%s – %s", start.Format("2006"), end.Format("2006"))
+	case DatePrecisionMonth:
+		if start.Year() == end.Year() && start.Month() == end.Month() {
+			return start.Format("January 2006")
+		} else if start.Year() == end.Year() {
+			return fmt.Sprintf("%s – %s", start.Format("January"), end.Format("January 2006"))
+		}
+		return fmt.Sprintf("%s – %s", start.Format("January 2006"), end.Format("January 2006"))
+	case DatePrecisionDay:
+		if start.Equal(end) {
+			return start.Format("2 January 2006")
+		} else if start.Year() == end.Year() && start.Month() == end.Month() {
+			return fmt.Sprintf("%s – %s", start.Format("2"), end.Format("2 January 2006"))
+		} else if start.Year() == end.Year() {
+			return fmt.Sprintf("%s – %s", start.Format("2 January"), end.Format("2 January 2006"))
+		}
+		return fmt.Sprintf("%s – %s", start.Format("2 January 2006"), end.Format("2 January 2006"))
+	}
+
+	return ""
+}
+
+// ExportEntryDate returns Year and Day / Month values that ParseEntryDate reads back into the same date.
+func ExportEntryDate(start, end time.Time, precision DatePrecision) (year, dayMonth string) {
+	if end.Before(start) {
+		end = start
+	}
+
+	year = start.Format("2006")
+	if end.Year() != start.Year() {
+		year = fmt.Sprintf("%s-%s", start.Format("2006"), end.Format("2006"))
+	}
+
+	switch precision {
+	case DatePrecisionYear:
+		return year, "N/A"
+	case DatePrecisionMonth:
+		if start.Equal(end) {
+			return year, start.Format("January")
+		}
+		return year, fmt.Sprintf("%s - %s", start.Format("January"), end.Format("January"))
+	case DatePrecisionDay:
+		if start.Equal(end) {
+			return year, start.Format("2 January")
+		}
+		return year, fmt.Sprintf("%s - %s", start.Format("2 January"), end.Format("2 January"))
+	}
+
+	return "N/A", "N/A"
+}
@@ -0,0 +1,47 @@
+package yps
+
+import "testing"
+
+func TestParseEntryDateYears(t *testing.T) {
+	tests := []struct {
+		rawYear   string
+		start     string
+		end       string
+		precision DatePrecision
+		wantErr   bool
+	}{
+		{"2019", "2019-01-01", "2019-01-01", DatePrecisionYear, false},
+		{"2019-21", "2019-01-01", "2021-01-01", DatePrecisionYear, false},
+		{"2019 - 2021", "2019-01-01", "2021-01-01", DatePrecisionYear, false},
+		{"2011-12", "2011-01-01", "2012-01-01", DatePrecisionYear, false},
+		{"2019-12", "2019-12-01", "2019-12-01", DatePrecisionMonth, false},
+		{"2019-05", "2019-05-01", "2019-05-01", DatePrecisionMonth, false},
+		{"2019-5", "2019-05-01", "2019-05-01", DatePrecisionMonth, false},
+		{"2019-05-14", "2019-05-14", "2019-05-14", DatePrecisionDay, false},
+		{"2019-13", "", "", DatePrecisionUnknown, true},
+		{"2019-00", "", "", DatePrecisionUnknown, true},
+	}
+
+	for _, test := range tests {
+		date, err := ParseEntryDate(test.rawYear, "")
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %v", test.rawYear, date)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.rawYear, err)
+			continue
+		}
+		if date.Precision != test.precision {
+			t.Errorf("%s: precision is %s, expected %s", test.rawYear, date.Precision, test.precision)
+		}
+		if start := date.Start.Format("2006-01-02"); start != test.start {
+			t.Errorf("%s: start is %s, expected %s", test.rawYear, start, test.start)
+		}
+		if end := date.End.Format("2006-01-02"); end != test.end {
+			t.Errorf("%s: end is %s, expected %s", test.rawYear, end, test.end)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package yps
+
+import (
+	"cmp"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultDuplicateScore = 0.6
+	maxDuplicateResults   = 500
+
+	// how much each kind of match adds to a pair's score. title similarity is
+	// scaled by how similar they are, and author overlap by how many are shared
+	duplicateTitleWeight    = 0.6
+	duplicateURLWeight      = 0.4
+	duplicateOrgDocIDWeight = 0.4
+	duplicateAuthorsWeight  = 0.2
+)
+
+// DuplicateCandidate is a pair of entries that look like the same document.
+type DuplicateCandidate struct {
+	EntryID    string   `json:"entry_id"`
+	EntryTitle string   `json:"entry_title"`
+	OtherID    string   `json:"other_id"`
+	OtherTitle string   `json:"other_title"`
+	Score      float64  `json:"score"`
+	Reasons    []string `json:"reasons"`
+}
+
+// duplicateFields are the parts of an entry that pairs are compared on,
+// normalised ahead of time so each pair is cheap to score.
+type duplicateFields struct {
+	ID       string
+	Title    string
+	trigrams []int
+	url      string
+	orgDocID string
+	authors  []string
+	altIDs   []string
+}
+
+// duplicateTrigrams gives each distinct trigram a number, so titles can be
+// compared as sorted lists of ints.
+type duplicateTrigrams map[string]int
+
+// titleTrigrams splits the title into trigrams the same way pg_trgm does,
+// padding each word so that its start and end count for more.
+func (known duplicateTrigrams) titleTrigrams(title string) (trigrams []int) {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			trigram := string(padded[i : i+3])
+			number, exists := known[trigram]
+			if !exists {
+				number = len(known)
+				known[trigram] = number
+			}
+			trigrams = append(trigrams, number)
+		}
+	}
+	slices.Sort(trigrams)
+	return slices.Compact(trigrams)
+}
+
+func normaliseDuplicateURL(url string) string {
+	url = strings.ToLower(strings.TrimSpace(url))
+	url = strings.TrimPrefix(url, "https://")
+	url = strings.TrimPrefix(url, "http://")
+	url = strings.TrimPrefix(url, "www.")
+	return strings.TrimRight(url, "/")
+}
+
+func (known duplicateTrigrams) fields(id, title, url, orgDocID string, authors, altIDs []string) duplicateFields {
+	fields := duplicateFields{
+		ID:       id,
+		Title:    title,
+		trigrams: known.titleTrigrams(title),
+		url:      normaliseDuplicateURL(url),
+		orgDocID: NormaliseVocabularyValue(orgDocID),
+		altIDs:   altIDs,
+	}
+	for _, author := range authors {
+		author = NormaliseVocabularyValue(author)
+		if author != "" && !slices.Contains(fields.authors, author) {
+			fields.authors = append(fields.authors, author)
+		}
+	}
+	return fields
+}
+
+func (known duplicateTrigrams) entryFields(entry Entry) duplicateFields {
+	return known.fields(entry.ItemID, entry.Title, entry.URL, entry.OrgDocID, entry.Authors, entry.AltLanguageIDs)
+}
+
+func (known duplicateTrigrams) xlsxEntryFields(entry XlsxEntry) duplicateFields {
+	return known.fields(entry.ItemID, entry.Title, entry.URL, entry.OrgDocID, entry.Authors, entry.AltLanguageIDs)
+}
+
+// trigramSimilarity is the share of trigrams the two sorted lists have in common.
+func trigramSimilarity(a, b []int) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	var shared, i, j int
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			shared++
+			i++
+			j++
+		} else if a[i] < b[j] {
+			i++
+		} else {
+			j++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// scoreDuplicatePair scores how likely it is that the two entries are the
+// same document, from 0 to 1. Below minScore, the score and reasons are only
+// worked out as far as needed to know it can't reach minScore.
+func scoreDuplicatePair(a, b *duplicateFields, minScore float64) (score float64, reasons []string) {
+	// translations of each other share a lot, but aren't duplicates
+	if slices.Contains(a.altIDs, b.ID) || slices.Contains(b.altIDs, a.ID) {
+		return 0, nil
+	}
+
+	if a.url != "" && a.url == b.url {
+		score += duplicateURLWeight
+		reasons = append(reasons, "same URL")
+	}
+	if a.orgDocID != "" && a.orgDocID == b.orgDocID {
+		score += duplicateOrgDocIDWeight
+		reasons = append(reasons, "same org doc ID")
+	}
+
+	var sharedAuthors int
+	for _, author := range a.authors {
+		if slices.Contains(b.authors, author) {
+			sharedAuthors++
+		}
+	}
+	if sharedAuthors > 0 {
+		score += duplicateAuthorsWeight * float64(sharedAuthors) / float64(min(len(a.authors), len(b.authors)))
+		reasons = append(reasons, "shared authors")
+	}
+
+	// titles can't be more similar than their lengths allow, so most pairs stop here
+	maxTitleSimilarity := float64(min(len(a.trigrams), len(b.trigrams))) / float64(max(len(a.trigrams), len(b.trigrams), 1))
+	if score+duplicateTitleWeight*maxTitleSimilarity < minScore {
+		return score, reasons
+	}
+
+	titleSimilarity := trigramSimilarity(a.trigrams, b.trigrams)
+	if titleSimilarity >= 0.5 {
+		reasons = append(reasons, fmt.Sprintf("titles %.0f%% similar", titleSimilarity*100))
+	}
+	score += duplicateTitleWeight * titleSimilarity
+
+	return math.Min(score, 1), reasons
+}
+
+// sortDuplicateCandidates puts the most likely duplicates first.
+func sortDuplicateCandidates(candidates []DuplicateCandidate) {
+	slices.SortFunc(candidates, func(a, b DuplicateCandidate) int {
+		if a.Score != b.Score {
+			return cmp.Compare(b.Score, a.Score)
+		}
+		if a.EntryID != b.EntryID {
+			return compareItemIDs(a.EntryID, b.EntryID)
+		}
+		return compareItemIDs(a.OtherID, b.OtherID)
+	})
+}
+
+// findDuplicateEntries compares every pair of entries and returns the ones
+// scoring at least minScore.
+func findDuplicateEntries(entries map[string]Entry, minScore float64) []DuplicateCandidate {
+	known := make(duplicateTrigrams)
+	var fields []duplicateFields
+	for _, entry := range entries {
+		fields = append(fields, known.entryFields(entry))
+	}
+	slices.SortFunc(fields, func(a, b duplicateFields) int {
+		return compareItemIDs(a.ID, b.ID)
+	})
+
+	candidates := []DuplicateCandidate{}
+	for i := range fields {
+		for j := i + 1; j < len(fields); j++ {
+			score, reasons := scoreDuplicatePair(&fields[i], &fields[j], minScore)
+			if score >= minScore {
+				candidates = append(candidates, DuplicateCandidate{
+					EntryID:    fields[i].ID,
+					EntryTitle: fields[i].Title,
+					OtherID:    fields[j].ID,
+					OtherTitle: fields[j].Title,
+					Score:      score,
+					Reasons:    reasons,
+				})
+			}
+		}
+	}
+
+	sortDuplicateCandidates(candidates)
+	return candidates
+}
+
+// findNewDuplicateEntries compares the sheet's new rows against every other
+// entry there'll be once the sheet is imported, and returns nits for likely
+// duplicates.
+func findNewDuplicateEntries(sheetEntries map[string]XlsxEntry, existingEntries map[string]Entry, mode ImportMode) (nits []string) {
+	known := make(duplicateTrigrams)
+	var newFields, otherFields []duplicateFields
+	for id, entry := range sheetEntries {
+		if _, exists := existingEntries[id]; exists {
+			otherFields = append(otherFields, known.xlsxEntryFields(entry))
+		} else {
+			newFields = append(newFields, known.xlsxEntryFields(entry))
+		}
+	}
+	if len(newFields) == 0 {
+		return nil
+	}
+	if mode != ImportModeReplace {
+		for id, entry := range existingEntries {
+			if _, exists := sheetEntries[id]; !exists {
+				otherFields = append(otherFields, known.entryFields(entry))
+			}
+		}
+	}
+
+	var candidates []DuplicateCandidate
+	for i := range newFields {
+		// new rows are compared to each other once, and to everything else
+		others := slices.Concat(newFields[i+1:], otherFields)
+		for j := range others {
+			score, reasons := scoreDuplicatePair(&newFields[i], &others[j], defaultDuplicateScore)
+			if score >= defaultDuplicateScore {
+				candidates = append(candidates, DuplicateCandidate{
+					EntryID: newFields[i].ID,
+					OtherID: others[j].ID,
+					Score:   score,
+					Reasons: reasons,
+				})
+			}
+		}
+	}
+
+	sortDuplicateCandidates(candidates)
+	for _, candidate := range candidates {
+		nits = append(nits, fmt.Sprintf("[Items %s, %s] New row looks like a duplicate (%s).", candidate.EntryID, candidate.OtherID, strings.Join(candidate.Reasons, ", ")))
+	}
+	return nits
+}
+
+// handler
+
+type GetDuplicatesRequest struct {
+	MinScore float64 `form:"min_score"`
+}
+
+type GetDuplicatesResponse struct {
+	Candidates []DuplicateCandidate `json:"candidates"`
+	// whether there were more than maxDuplicateResults
+	Truncated bool `json:"truncated"`
+}
+
+func getDuplicates(c *gin.Context) {
+	var req GetDuplicatesRequest
+	if err := c.ShouldBind(&req); err != nil {
+		fmt.Println("Could not get duplicates binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not read params"})
+		return
+	}
+	if req.MinScore <= 0 || req.MinScore > 1 {
+		req.MinScore = defaultDuplicateScore
+	}
+
+	entries, err := TheDb.GetAllEntries()
+	if err != nil {
+		fmt.Println("Could not get entries:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get entries"})
+		return
+	}
+
+	response := GetDuplicatesResponse{
+		Candidates: findDuplicateEntries(entries, req.MinScore),
+	}
+	if len(response.Candidates) > maxDuplicateResults {
+		response.Candidates = response.Candidates[:maxDuplicateResults]
+		response.Truncated = true
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+type MergeEntriesParams struct {
+	// the surviving ID
+	Keep   string `json:"keep" binding:"required"`
+	Remove string `json:"remove" binding:"required"`
+}
+
+func mergeEntries(c *gin.Context) {
+	var params MergeEntriesParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	params.Keep = strings.TrimSpace(params.Keep)
+	params.Remove = strings.TrimSpace(params.Remove)
+	if params.Keep == params.Remove {
+		c.JSON(400, gin.H{"error": "An entry can't be merged into itself"})
+		return
+	}
+
+	lock := acquireImportLockOrConflict(c, fmt.Sprintf("merge of entry %s into %s", params.Remove, params.Keep))
+	if lock == nil {
+		return
+	}
+	defer lock.Release()
+
+	err := TheDb.MergeEntries(params.Keep, params.Remove, EntryChange{
+		Source: "merge",
+		Actor:  requestActor(c),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Both entries must exist"})
+		return
+	} else if err != nil {
+		fmt.Println("Could not merge entries:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not merge entries"})
+		return
+	}
+
+	err = UpdateBrowseByFields()
+	if err != nil {
+		fmt.Println("Could not update browse-by fields:", err.Error())
+	}
+
+	Log(LogLevelInfo, "entry-merge", fmt.Sprintf("Merged entry %s into %s", params.Remove, params.Keep), params)
+
+	luEntry, err := TheDb.GetSingleEntry(params.Keep, true)
+	if err != nil {
+		fmt.Println("Could not get entry:", params.Keep, ":", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get entry"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok":    true,
+		"entry": luEntry.AsEntryResponse(),
+	})
+}
@@ -2,34 +2,80 @@ package yps
 
 import (
 	"bytes"
+	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type ImportTryResponse struct {
-	TotalEntries      int      `json:"total_entries"`
-	UnmodifiedEntries int      `json:"unmodified_entries"`
-	ModifiedEntries   int      `json:"modified_entries"`
-	NewEntries        int      `json:"new_entries"`
-	DeletedEntries    int      `json:"deleted_entries"`
-	Nits              []string `json:"nits"`
-	FileAlreadyExists bool     `json:"file_already_exists"`
+	Mode              ImportMode         `json:"mode"`
+	Options           ReadEntriesOptions `json:"options"`
+	TotalEntries      int                `json:"total_entries"`
+	UnmodifiedEntries int                `json:"unmodified_entries"`
+	ModifiedEntries   int                `json:"modified_entries"`
+	NewEntries        int                `json:"new_entries"`
+	DeletedEntries    int                `json:"deleted_entries"`
+	SkippedEntries    int                `json:"skipped_entries"`
+	Nits              []string           `json:"nits"`
+	FileAlreadyExists bool               `json:"file_already_exists"`
+
+	Normalisations []VocabularyNormalisation `json:"normalisations"`
 }
 
+// TheBrowseByFields only covers public entries. It's refreshed daily as well
+// as on changes, so entries coming out of embargo show up.
 var TheBrowseByFields *BrowseByFieldValues
+var theBrowseByFieldsDate string
+
+// requests, imports and edits all update the browse-by fields, so they're
+// only read and written while holding this
+var theBrowseByFieldsLock sync.Mutex
 
 func UpdateBrowseByFields() error {
-	bbf, err := TheDb.GetBrowseByFields()
+	// this runs whenever entries change, so similar entries need working out again too
+	clearSimilarEntries()
+
+	bbf, err := TheDb.GetBrowseByFields(false)
 	if err != nil {
 		fmt.Println("Failed to update browse by fields:", err)
 	} else {
+		theBrowseByFieldsLock.Lock()
 		TheBrowseByFields = &bbf
+		theBrowseByFieldsDate = time.Now().Format(time.DateOnly)
+		theBrowseByFieldsLock.Unlock()
 	}
 	return err
 }
 
+// currentBrowseByFields returns the public browse-by fields, refreshing them
+// first if they're from an earlier day.
+func currentBrowseByFields() (*BrowseByFieldValues, error) {
+	theBrowseByFieldsLock.Lock()
+	values := TheBrowseByFields
+	stale := theBrowseByFieldsDate != time.Now().Format(time.DateOnly)
+	theBrowseByFieldsLock.Unlock()
+	if values != nil && !stale {
+		return values, nil
+	}
+
+	err := UpdateBrowseByFields()
+	if err != nil && values == nil {
+		return nil, err
+	}
+
+	theBrowseByFieldsLock.Lock()
+	defer theBrowseByFieldsLock.Unlock()
+	return TheBrowseByFields, nil
+}
+
 func updateYpsDb(c *gin.Context) {
 	// whether to apply the changes or not
 	_, apply := c.GetQuery("apply")
@@ -46,6 +92,12 @@ func applyYpsDbUpdate(c *gin.Context) {
 	overwriteRaw, exists := c.GetQuery("overwrite")
 	overwrite := exists && overwriteRaw == "true"
 
+	mode, err := ParseImportMode(c.Query("mode"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
 	// load passed db file
 	fileHeader, err := c.FormFile("db")
 	if err != nil {
@@ -76,37 +128,31 @@ func applyYpsDbUpdate(c *gin.Context) {
 		return
 	}
 
-	err = TheDb.UploadDbFile(s3fn, bytes.NewReader(buf.Bytes()))
-	if err != nil {
-		fmt.Println("Could not upload new db file:", err.Error())
-		c.JSON(400, gin.H{"error": "Could not upload 'db' file from form body."})
+	lock := acquireImportLockOrConflict(c, "database import of "+fileHeader.Filename)
+	if lock == nil {
 		return
 	}
 
-	newEntries, err := ReadEntriesFile(bytes.NewReader(buf.Bytes()))
+	job, err := TheImportJobs.Start(fileHeader.Filename, mode, parseReadEntriesOptions(c))
 	if err != nil {
-		fmt.Println("Could not read entries file:", err.Error())
-		c.JSON(400, gin.H{"error": err.Error()})
+		lock.Release()
+		fmt.Println("Could not start import job:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not start import."})
 		return
 	}
 
-	err = TheDb.UploadEntries(newEntries.Entries)
+	go runImportJob(job.ID, lock, s3fn, buf)
+
+	c.JSON(http.StatusAccepted, gin.H{"ok": true, "job": job})
+}
+
+func testYpsDbUpdate(c *gin.Context) {
+	mode, err := ParseImportMode(c.Query("mode"))
 	if err != nil {
-		fmt.Println("Could not upload entries:", err.Error())
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
-	Log(LogLevelInfo, "database-update", "Applied database update", map[string]string{
-		"filename": fileHeader.Filename,
-	})
-
-	//TODO(dan): upload xlsx file to S3, etc…
-
-	c.JSON(200, gin.H{"ok": true})
-}
-
-func testYpsDbUpdate(c *gin.Context) {
 	// load passed db file
 	fileHeader, err := c.FormFile("db")
 	if err != nil {
@@ -129,21 +175,30 @@ func testYpsDbUpdate(c *gin.Context) {
 		return
 	}
 
-	newEntries, err := ReadEntriesFile(file)
+	existingEntries, err := TheDb.GetAllEntries()
 	if err != nil {
-		fmt.Println("Could not read entries file:", err.Error())
+		fmt.Println("Could not existing entries:", err.Error())
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
-	existingEntries, err := TheDb.GetAllEntries()
+	lookups, err := getImportLookups(mode, existingEntries)
 	if err != nil {
-		fmt.Println("Could not existing entries:", err.Error())
+		fmt.Println("Could not get import lookups:", err.Error())
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
-	var unmodifiedEntriesCount, modifiedEntriesCount, newEntriesCount, deletedEntriesCount int
+	options := parseReadEntriesOptions(c)
+	newEntries, err := ReadEntriesFile(file, lookups, options)
+	if err != nil {
+		fmt.Println("Could not read entries file:", err.Error())
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	var unmodifiedEntriesCount, modifiedEntriesCount, newEntriesCount, deletedEntriesCount, skippedEntriesCount int
+	var manuallyEditedIDs []string
 	for id, newEntry := range newEntries.Entries {
 		oldEntry, exists := existingEntries[id]
 		if !exists {
@@ -151,28 +206,54 @@ func testYpsDbUpdate(c *gin.Context) {
 			continue
 		}
 
-		if newEntry.Matches(oldEntry) {
+		if mode == ImportModeAppend {
+			skippedEntriesCount += 1
+		} else if newEntry.Matches(oldEntry) {
 			unmodifiedEntriesCount += 1
 		} else {
 			modifiedEntriesCount += 1
+			if oldEntry.ManuallyEditedAt != nil {
+				manuallyEditedIDs = append(manuallyEditedIDs, id)
+			}
 		}
 	}
 
-	for id := range existingEntries {
-		_, exists := newEntries.Entries[id]
-		if !exists {
-			deletedEntriesCount += 1
+	if mode == ImportModeReplace {
+		for id, oldEntry := range existingEntries {
+			_, exists := newEntries.Entries[id]
+			if !exists {
+				deletedEntriesCount += 1
+				if oldEntry.ManuallyEditedAt != nil {
+					manuallyEditedIDs = append(manuallyEditedIDs, id)
+				}
+			}
 		}
 	}
 
+	// entries edited through the site since the last import would lose those changes
+	if len(manuallyEditedIDs) > 0 {
+		slices.SortFunc(manuallyEditedIDs, compareItemIDs)
+		itemsLabel := "Item"
+		if len(manuallyEditedIDs) > 1 {
+			itemsLabel = "Items"
+		}
+		newEntries.Nits = append(newEntries.Nits, fmt.Sprintf("[%s %s] Edited by hand since the last import, these changes will be overwritten.", itemsLabel, strings.Join(manuallyEditedIDs, ", ")))
+	}
+
+	newEntries.Nits = append(newEntries.Nits, findNewDuplicateEntries(newEntries.Entries, existingEntries, mode)...)
+
 	response := ImportTryResponse{
+		Mode:              mode,
+		Options:           options,
 		TotalEntries:      len(newEntries.Entries),
 		UnmodifiedEntries: unmodifiedEntriesCount,
 		ModifiedEntries:   modifiedEntriesCount,
 		NewEntries:        newEntriesCount,
 		DeletedEntries:    deletedEntriesCount,
+		SkippedEntries:    skippedEntriesCount,
 		Nits:              newEntries.Nits,
 		FileAlreadyExists: alreadyExists,
+		Normalisations:    newEntries.Normalisations,
 	}
 
 	Log(LogLevelInfo, "database-update-test", "Tested database update", response)
@@ -229,7 +310,7 @@ type GetLatestDbResponse struct {
 }
 
 func getLatestYpsDb(c *gin.Context) {
-	info, err := TheDb.GetLatestDbInfo()
+	info, err := TheDb.GetLatestDbInfo(isAdminRequest(c))
 	if err != nil {
 		fmt.Println("Could not get db info:", err.Error())
 		c.JSON(400, gin.H{"error": "Could not get db info"})
@@ -255,8 +336,11 @@ func getEntry(c *gin.Context) {
 		return
 	}
 
-	luEntry, err := TheDb.GetSingleEntry(req.ID)
-	if err != nil {
+	luEntry, err := TheDb.GetSingleEntry(req.ID, isAdminRequest(c))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondMissingEntry(c, req.ID)
+		return
+	} else if err != nil {
 		fmt.Println("Could not get entry:", req.ID, ":", err.Error())
 		c.JSON(400, gin.H{"error": "Could not get entry"})
 		return
@@ -267,6 +351,121 @@ func getEntry(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+const maxBatchEntries = 200
+
+type GetEntriesRequest struct {
+	// comma-separated, for GET requests
+	IDList string `form:"ids"`
+}
+
+type GetEntriesParams struct {
+	IDs []string `json:"ids" binding:"required"`
+}
+
+type GetEntriesResponse struct {
+	// in the order they were asked for
+	Entries  []GetEntryResponse `json:"entries"`
+	NotFound []string           `json:"not_found"`
+}
+
+// getEntries looks up many entries at once, given either as the ids query
+// param or in a POST body for lists too long for a URL.
+func getEntries(c *gin.Context) {
+	var ids []string
+	if c.Request.Method == http.MethodPost {
+		var params GetEntriesParams
+		if err := c.ShouldBindJSON(&params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ids = params.IDs
+	} else {
+		var req GetEntriesRequest
+		if err := c.ShouldBind(&req); err != nil {
+			fmt.Println("Could not get entries binding:", err.Error())
+			c.JSON(400, gin.H{"error": "Entries must be given"})
+			return
+		}
+		ids = strings.Split(req.IDList, ",")
+	}
+
+	var cleanIDs []string
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		cleanIDs = append(cleanIDs, id)
+
+		// stop early so huge lists don't take long to turn away
+		if len(cleanIDs) > maxBatchEntries {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("At most %d entries can be looked up at once", maxBatchEntries)})
+			return
+		}
+	}
+	if len(cleanIDs) == 0 {
+		c.JSON(400, gin.H{"error": "Entries must be given"})
+		return
+	}
+
+	luEntries, err := TheDb.GetEntries(cleanIDs, isAdminRequest(c))
+	if err != nil {
+		fmt.Println("Could not get entries:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get entries"})
+		return
+	}
+
+	response := GetEntriesResponse{
+		Entries:  []GetEntryResponse{},
+		NotFound: []string{},
+	}
+	for _, id := range cleanIDs {
+		luEntry, exists := luEntries[id]
+		if exists {
+			response.Entries = append(response.Entries, luEntry.AsEntryResponse())
+		} else {
+			response.NotFound = append(response.NotFound, id)
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// respondMissingEntry redirects old IDs to the entry they lead to now, and
+// says when and why deleted entries were removed.
+func respondMissingEntry(c *gin.Context, id string) {
+	redirectTo, tombstone, err := TheDb.ResolveMissingEntry(id)
+	if err != nil {
+		fmt.Println("Could not resolve missing entry:", id, ":", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get entry"})
+		return
+	}
+	if tombstone != nil && tombstone.ReplacedBy != "" {
+		redirectTo = tombstone.ReplacedBy
+	}
+
+	if redirectTo != "" {
+		c.Header("Location", "/api/entry/"+url.PathEscape(redirectTo))
+		c.JSON(http.StatusMovedPermanently, gin.H{
+			"error":       fmt.Sprintf("Item %s is now item %s", id, redirectTo),
+			"redirect_to": redirectTo,
+			"tombstone":   tombstone,
+		})
+		return
+	}
+	if tombstone != nil {
+		c.JSON(http.StatusGone, gin.H{
+			"error":     fmt.Sprintf("Item %s has been removed", id),
+			"tombstone": tombstone,
+		})
+		return
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "Entry not found"})
+}
+
 type UploadEntryFileRequest struct {
 	ID string `uri:"slug" binding:"required"`
 }
@@ -373,8 +572,28 @@ type BrowseByFieldsResponse struct {
 }
 
 func getBrowseByFields(c *gin.Context) {
+	if isAdminRequest(c) {
+		values, err := TheDb.GetBrowseByFields(true)
+		if err != nil {
+			fmt.Println("Could not get browse by fields:", err.Error())
+			c.JSON(400, gin.H{"error": "Could not get browse by fields"})
+			return
+		}
+		c.JSON(http.StatusOK, BrowseByFieldsResponse{
+			Values: values,
+		})
+		return
+	}
+
+	values, err := currentBrowseByFields()
+	if err != nil {
+		fmt.Println("Could not get browse by fields:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get browse by fields"})
+		return
+	}
+
 	c.JSON(http.StatusOK, BrowseByFieldsResponse{
-		Values: *TheBrowseByFields,
+		Values: *values,
 	})
 }
 
@@ -389,6 +608,12 @@ func importFileList(c *gin.Context) {
 		return
 	}
 
+	lock := acquireImportLockOrConflict(c, "entry file list import")
+	if lock == nil {
+		return
+	}
+	defer lock.Release()
+
 	err := TheDb.ImportFileList(FileList(params))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
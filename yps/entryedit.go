@@ -0,0 +1,364 @@
+package yps
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	ypsc "github.com/YPS-Database/yps-db-backend/yps/columns"
+	"github.com/gin-gonic/gin"
+)
+
+// EditEntryParams are the fields of an entry that can be set directly. Fields
+// that aren't given are left as they are.
+type EditEntryParams struct {
+	Title           *string  `json:"title"`
+	Authors         []string `json:"authors"`
+	AuthorsEtAl     *bool    `json:"authors_et_al"`
+	URL             *string  `json:"url"`
+	OrgPublishers   []string `json:"orgs"`
+	OrgDocID        *string  `json:"org_doc_id"`
+	OrgType         *string  `json:"org_type"`
+	DocType         *string  `json:"entry_type"`
+	Abstract        *string  `json:"abstract"`
+	YouthLedDetails *string  `json:"youth_led_details"`
+	Keywords        []string `json:"keywords"`
+	Regions         []string `json:"regions"`
+	// dates are given the same way as the spreadsheet's year and day/month columns
+	Year     *string `json:"year"`
+	DayMonth *string `json:"day_month"`
+	// language names, like the spreadsheet's languages column
+	Languages      []string `json:"languages"`
+	AltLanguageIDs []string `json:"alt_language_ids"`
+	// item IDs, or typed links like 'supersedes:123'
+	Related     []string `json:"related"`
+	PreviousIDs []string `json:"previous_ids"`
+	// embargo_until is a date like '2025-06-01', and only applies to embargoed entries
+	Visibility   *EntryVisibility `json:"visibility"`
+	EmbargoUntil *string          `json:"embargo_until"`
+	Notes        *string          `json:"notes"`
+}
+
+// entryColumns applies the params on top of the given entry, and returns the
+// result as the spreadsheet columns that ReadEntryColumns expects.
+func (params *EditEntryParams) entryColumns(entry Entry) (map[ypsc.ColumnType]string, error) {
+	if params.Title != nil {
+		entry.Title = *params.Title
+	}
+	if params.Authors != nil {
+		entry.Authors = params.Authors
+	}
+	if params.AuthorsEtAl != nil {
+		entry.AuthorsEtAl = *params.AuthorsEtAl
+	}
+	if params.URL != nil {
+		entry.URL = *params.URL
+	}
+	if params.OrgPublishers != nil {
+		entry.OrgPublishers = params.OrgPublishers
+	}
+	if params.OrgDocID != nil {
+		entry.OrgDocID = *params.OrgDocID
+	}
+	if params.OrgType != nil {
+		entry.OrgType = *params.OrgType
+	}
+	if params.DocType != nil {
+		entry.DocType = *params.DocType
+	}
+	if params.Abstract != nil {
+		entry.Abstract = *params.Abstract
+	}
+	if params.YouthLedDetails != nil {
+		entry.YouthLedDetails = *params.YouthLedDetails
+	}
+	if params.Keywords != nil {
+		entry.Keywords = params.Keywords
+	}
+	if params.Regions != nil {
+		for _, region := range params.Regions {
+			if !slices.ContainsFunc(ypsc.RegionColumns, func(columnType ypsc.ColumnType) bool {
+				return columnType.String() == region
+			}) {
+				return nil, fmt.Errorf("[%s] is not a known region", region)
+			}
+		}
+		entry.Regions = params.Regions
+	}
+	if params.AltLanguageIDs != nil {
+		entry.AltLanguageIDs = params.AltLanguageIDs
+	}
+	if params.PreviousIDs != nil {
+		entry.PreviousIDs = params.PreviousIDs
+	}
+	if params.Notes != nil {
+		entry.Notes = *params.Notes
+	}
+
+	columns := exportColumns(entry)
+	if params.Year != nil {
+		columns[ypsc.Year] = *params.Year
+	}
+	if params.DayMonth != nil {
+		columns[ypsc.DayMonth] = *params.DayMonth
+	}
+	if params.Languages != nil {
+		columns[ypsc.Languages] = strings.Join(params.Languages, ", ")
+	}
+	if params.Related != nil {
+		columns[ypsc.RelatedEntries] = strings.Join(params.Related, ", ")
+	}
+	keepEmbargoDate := params.Visibility != nil && *params.Visibility == EntryVisibilityEmbargoed &&
+		params.EmbargoUntil == nil && entry.Visibility == EntryVisibilityEmbargoed
+	if params.Visibility != nil && !keepEmbargoDate {
+		columns[ypsc.Visibility] = string(*params.Visibility)
+	} else if params.EmbargoUntil != nil {
+		columns[ypsc.Visibility] = string(EntryVisibilityEmbargoed)
+	}
+	if params.EmbargoUntil != nil && EntryVisibility(columns[ypsc.Visibility]) == EntryVisibilityEmbargoed {
+		columns[ypsc.Visibility] += " until " + *params.EmbargoUntil
+	}
+
+	return columns, nil
+}
+
+// saveEntryFromParams reads the params on top of the given entry with the
+// same rules as a spreadsheet import, and saves the result. If it can't be
+// saved, an error response is written and saved is false. The import lock
+// must be held, and entry and allEntries loaded after taking it, so an import
+// can't change them in between.
+func saveEntryFromParams(c *gin.Context, source string, entry Entry, params EditEntryParams, allEntries map[string]Entry) (newEntries *EntriesXLSX, saved bool) {
+	columns, err := params.entryColumns(entry)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	lookups, err := getImportLookups(ImportModeUpsert, allEntries)
+	if err != nil {
+		fmt.Println("Could not get import lookups:", err.Error())
+		c.JSON(400, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	newEntries, err = ReadEntryColumns(entry.ItemID, columns, lookups, ReadEntriesOptions{})
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	err = TheDb.UploadEntries(newEntries.Entries, ImportModeUpsert, EntryChange{
+		Source: source,
+		Actor:  requestActor(c),
+	}, nil)
+	if err != nil {
+		fmt.Println("Could not save entry:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not save entry"})
+		return nil, false
+	}
+
+	err = TheDb.MarkEntryManuallyEdited(entry.ItemID)
+	if err != nil {
+		fmt.Println("Could not mark entry as manually edited:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not save entry"})
+		return nil, false
+	}
+
+	return newEntries, true
+}
+
+// respondSavedEntry returns the entry as it is now, along with anything
+// worth knowing from reading it in.
+func respondSavedEntry(c *gin.Context, id string, newEntries *EntriesXLSX) {
+	luEntry, err := TheDb.GetSingleEntry(id, true)
+	if err != nil {
+		fmt.Println("Could not get entry:", id, ":", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get entry"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok":             true,
+		"entry":          luEntry.AsEntryResponse(),
+		"nits":           newEntries.Nits,
+		"normalisations": newEntries.Normalisations,
+	})
+}
+
+// checkNewEntryID returns the cleaned up ID, or an error if it can't be used for a new entry.
+func checkNewEntryID(id string, allEntries map[string]Entry) (string, error) {
+	// commas and colons would break the alternate and related columns
+	id = strings.TrimSpace(id)
+	if id == "" || strings.ContainsAny(id, ",:") {
+		return id, errors.New("Item ID can't be blank or contain commas or colons")
+	}
+	if _, exists := allEntries[id]; exists {
+		return id, fmt.Errorf("Item %s already exists", id)
+	}
+	return id, nil
+}
+
+// handler
+
+type CreateEntryParams struct {
+	ID string `json:"id" binding:"required"`
+	EditEntryParams
+}
+
+func createEntry(c *gin.Context) {
+	var params CreateEntryParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lock := acquireImportLockOrConflict(c, "creation of entry "+strings.TrimSpace(params.ID))
+	if lock == nil {
+		return
+	}
+	defer lock.Release()
+
+	allEntries, err := TheDb.GetAllEntries()
+	if err != nil {
+		fmt.Println("Could not get entries:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get entries"})
+		return
+	}
+	id, err := checkNewEntryID(params.ID, allEntries)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	newEntries, saved := saveEntryFromParams(c, "manual create", Entry{ItemID: id}, params.EditEntryParams, allEntries)
+	if !saved {
+		return
+	}
+
+	Log(LogLevelInfo, "entry-create", "Created entry "+id, params)
+
+	respondSavedEntry(c, id, newEntries)
+}
+
+func editEntry(c *gin.Context) {
+	var req GetEntryRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		fmt.Println("Could not get entry URI binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Entry must be given"})
+		return
+	}
+
+	var params EditEntryParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lock := acquireImportLockOrConflict(c, "edit of entry "+req.ID)
+	if lock == nil {
+		return
+	}
+	defer lock.Release()
+
+	allEntries, err := TheDb.GetAllEntries()
+	if err != nil {
+		fmt.Println("Could not get entries:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get entries"})
+		return
+	}
+	entry, exists := allEntries[req.ID]
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Entry not found"})
+		return
+	}
+
+	newEntries, saved := saveEntryFromParams(c, "manual edit", entry, params, allEntries)
+	if !saved {
+		return
+	}
+
+	Log(LogLevelInfo, "entry-update", "Updated entry "+req.ID, map[string]any{
+		"entry":   req.ID,
+		"changes": params,
+	})
+
+	respondSavedEntry(c, req.ID, newEntries)
+}
+
+// DeleteEntryParams are optional, and are shown to anyone following a link to the deleted entry.
+type DeleteEntryParams struct {
+	Reason     string `json:"reason"`
+	ReplacedBy string `json:"replaced_by"`
+}
+
+func deleteEntry(c *gin.Context) {
+	var req GetEntryRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		fmt.Println("Could not get entry URI binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Entry must be given"})
+		return
+	}
+
+	var params DeleteEntryParams
+	if err := c.ShouldBindJSON(&params); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	params.Reason = strings.TrimSpace(params.Reason)
+	params.ReplacedBy = strings.TrimSpace(params.ReplacedBy)
+	if params.ReplacedBy == req.ID {
+		c.JSON(400, gin.H{"error": "An entry can't be replaced by itself"})
+		return
+	}
+	if params.ReplacedBy != "" {
+		_, err := TheDb.GetSingleEntry(params.ReplacedBy, true)
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("Replacement item %s does not exist", params.ReplacedBy)})
+			return
+		} else if err != nil {
+			fmt.Println("Could not get replacement entry:", err.Error())
+			c.JSON(400, gin.H{"error": "Could not get replacement entry"})
+			return
+		}
+	}
+
+	lock := acquireImportLockOrConflict(c, "deletion of entry "+req.ID)
+	if lock == nil {
+		return
+	}
+	defer lock.Release()
+
+	err := TheDb.DeleteEntry(EntryTombstone{
+		ID:         req.ID,
+		Reason:     params.Reason,
+		ReplacedBy: params.ReplacedBy,
+	}, EntryChange{
+		Source: "manual delete",
+		Actor:  requestActor(c),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Entry not found"})
+		return
+	} else if err != nil {
+		fmt.Println("Could not delete entry:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not delete entry"})
+		return
+	}
+
+	err = UpdateBrowseByFields()
+	if err != nil {
+		fmt.Println("Could not update browse-by fields:", err.Error())
+	}
+
+	Log(LogLevelInfo, "entry-delete", "Deleted entry "+req.ID, map[string]string{
+		"entry":       req.ID,
+		"reason":      params.Reason,
+		"replaced_by": params.ReplacedBy,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
@@ -0,0 +1,198 @@
+package yps
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	ypsc "github.com/YPS-Database/yps-db-backend/yps/columns"
+	ypsl "github.com/YPS-Database/yps-db-backend/yps/languages"
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+const exportFilesSheet = "Files"
+
+// compareItemIDs sorts numeric IDs numerically, the same way the db's numeric collation does.
+func compareItemIDs(a, b string) int {
+	aNum, aErr := strconv.Atoi(a)
+	bNum, bErr := strconv.Atoi(b)
+	if aErr == nil && bErr == nil {
+		return aNum - bNum
+	}
+	return strings.Compare(a, b)
+}
+
+func exportRow(entry Entry) (row []any) {
+	year, dayMonth := ExportEntryDate(entry.StartDate, entry.EndDate, entry.DatePrecision)
+
+	for _, columnType := range ypsc.SheetColumns {
+		var value any
+		switch columnType {
+		case ypsc.ItemID:
+			value = entry.ItemID
+		case ypsc.Authors:
+			value = JoinAuthors(entry.Authors, entry.AuthorsEtAl)
+		case ypsc.Year:
+			value = year
+		case ypsc.Title:
+			value = entry.Title
+		case ypsc.OrgPublisher:
+			value = strings.Join(entry.OrgPublishers, "; ")
+		case ypsc.DocNumber:
+			value = entry.OrgDocID
+		case ypsc.DayMonth:
+			value = dayMonth
+		case ypsc.URL:
+			value = entry.URL
+		case ypsc.Languages:
+			// alternates each get their own row, so only this entry's language is listed
+			value = ypsl.GetName(entry.Language)
+		case ypsc.AlternateLanguageEntries:
+			value = strings.Join(entry.AltLanguageIDs, ", ")
+		case ypsc.RelatedEntries:
+			items := slices.Clone(entry.RelatedIDs)
+			for _, relation := range entry.Relations {
+				items = append(items, relation.Type+":"+relation.ID)
+			}
+			value = strings.Join(items, ", ")
+		case ypsc.YouthInvolvement:
+			value = entry.YouthLedDetails
+		case ypsc.Abstract:
+			value = entry.Abstract
+		case ypsc.OrgType:
+			value = entry.OrgType
+		case ypsc.DocType:
+			value = entry.DocType
+		case ypsc.Keywords:
+			value = strings.Join(entry.Keywords, "; ")
+		case ypsc.PreviousIDs:
+			value = strings.Join(entry.PreviousIDs, ", ")
+		case ypsc.Visibility:
+			value = FormatVisibility(entry.Visibility, entry.EmbargoUntil)
+		case ypsc.Notes:
+			value = entry.Notes
+		default:
+			if slices.Contains(ypsc.RegionColumns, columnType) {
+				value = 0
+				if slices.Contains(entry.Regions, columnType.String()) {
+					value = 1
+				}
+			}
+		}
+		row = append(row, value)
+	}
+
+	return row
+}
+
+// exportColumns returns the entry's values as ReadEntriesFile would see them in a spreadsheet.
+func exportColumns(entry Entry) map[ypsc.ColumnType]string {
+	columns := make(map[ypsc.ColumnType]string)
+	for i, value := range exportRow(entry) {
+		columns[ypsc.SheetColumns[i]] = fmt.Sprint(value)
+	}
+	return columns
+}
+
+// WriteExportFile returns a spreadsheet of the given entries in the layout that ReadEntriesFile expects.
+func WriteExportFile(entries map[string]Entry, files map[string][]EntryFile) (*bytes.Buffer, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	err := f.SetSheetName("Sheet1", templateDatabaseSheet)
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.NewSheet(exportFilesSheet)
+	if err != nil {
+		return nil, err
+	}
+
+	var headers []any
+	for _, columnType := range ypsc.SheetColumns {
+		headers = append(headers, columnType.String())
+	}
+	err = f.SetSheetRow(templateDatabaseSheet, "A1", &headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for id := range entries {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, compareItemIDs)
+
+	for i, id := range ids {
+		row := exportRow(entries[id])
+		err = f.SetSheetRow(templateDatabaseSheet, fmt.Sprintf("A%d", i+2), &row)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// files, in the same shape that the import-files endpoint takes
+	fileHeaders := []any{"Item", "Filename", "URL"}
+	err = f.SetSheetRow(exportFilesSheet, "A1", &fileHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	var fileIDs []string
+	for id := range files {
+		fileIDs = append(fileIDs, id)
+	}
+	slices.SortFunc(fileIDs, compareItemIDs)
+
+	fileRow := 2
+	for _, id := range fileIDs {
+		for _, file := range files[id] {
+			row := []any{id, file.Filename, file.URL}
+			err = f.SetSheetRow(exportFilesSheet, fmt.Sprintf("A%d", fileRow), &row)
+			if err != nil {
+				return nil, err
+			}
+			fileRow += 1
+		}
+	}
+
+	return f.WriteToBuffer()
+}
+
+// handler
+
+func exportYpsDb(c *gin.Context) {
+	entries, err := TheDb.GetAllEntries()
+	if err != nil {
+		fmt.Println("Could not get entries:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get entries"})
+		return
+	}
+
+	files, err := TheDb.GetAllEntryFiles()
+	if err != nil {
+		fmt.Println("Could not get entry files:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get entry files"})
+		return
+	}
+
+	buf, err := WriteExportFile(entries, files)
+	if err != nil {
+		fmt.Println("Could not write export file:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not create export file"})
+		return
+	}
+
+	Log(LogLevelInfo, "database-export", "Exported database", map[string]int{
+		"entries": len(entries),
+	})
+
+	filename := fmt.Sprintf("yps-database-%s.xlsx", time.Now().UTC().Format(time.DateOnly))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, xlsxContentType, buf.Bytes())
+}
@@ -0,0 +1,420 @@
+package yps
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// how many finished jobs are kept around to be looked up
+const MaxFinishedImportJobs = 20
+
+// key for the postgres advisory lock that's held while anything is using the temp import tables
+const ImportAdvisoryLockKey int64 = 797073
+
+type ImportMode string
+
+const (
+	// entries missing from the sheet are deleted
+	ImportModeReplace ImportMode = "replace"
+	// new entries are added and existing ones updated, nothing is deleted
+	ImportModeUpsert ImportMode = "upsert"
+	// only new entries are added, existing ones are left alone
+	ImportModeAppend ImportMode = "append"
+)
+
+func ParseImportMode(input string) (ImportMode, error) {
+	switch ImportMode(input) {
+	case "", ImportModeReplace:
+		return ImportModeReplace, nil
+	case ImportModeUpsert, ImportModeAppend:
+		return ImportMode(input), nil
+	}
+	return "", fmt.Errorf("import mode must be one of %s, %s or %s", ImportModeReplace, ImportModeUpsert, ImportModeAppend)
+}
+
+type ImportLockHolder struct {
+	Holder string    `json:"holder"`
+	Action string    `json:"action"`
+	Since  time.Time `json:"since"`
+}
+
+type ImportLock struct {
+	mu sync.Mutex
+
+	conn   *pgxpool.Conn
+	Holder ImportLockHolder
+}
+
+// Release gives up the import lock. It's safe to call this more than once.
+func (lock *ImportLock) Release() {
+	lock.mu.Lock()
+	defer lock.mu.Unlock()
+
+	if lock.conn == nil {
+		return
+	}
+
+	_, err := lock.conn.Exec(context.Background(), `delete from import_lock`)
+	if err != nil {
+		fmt.Println("Could not clear import lock holder:", err.Error())
+	}
+	_, err = lock.conn.Exec(context.Background(), `select pg_advisory_unlock($1)`, ImportAdvisoryLockKey)
+	if err != nil {
+		// dropping the connection drops the lock along with it
+		fmt.Println("Could not release import lock, closing connection:", err.Error())
+		lock.conn.Hijack().Close(context.Background())
+	} else {
+		lock.conn.Release()
+	}
+	lock.conn = nil
+}
+
+// requestActor describes the admin making the request, for locks and revisions.
+func requestActor(c *gin.Context) string {
+	return fmt.Sprintf("%s (%s)", c.GetString("level"), c.ClientIP())
+}
+
+// acquireImportLockOrConflict takes the import lock for the calling admin. If
+// it can't be taken, an error response is written and nil is returned.
+func acquireImportLockOrConflict(c *gin.Context, action string) *ImportLock {
+	lock, current, err := TheDb.AcquireImportLock(requestActor(c), action)
+	if err != nil {
+		fmt.Println("Could not acquire import lock:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not check whether another import is running."})
+		return nil
+	}
+	if current != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("Another import is already running (%s, started by %s at %s), please wait for it to finish.",
+				current.Action, current.Holder, current.Since.UTC().Format(time.RFC3339)),
+			"lock": current,
+		})
+		return nil
+	}
+
+	return lock
+}
+
+type ImportPhase string
+
+const (
+	ImportPhaseQueued       ImportPhase = "queued"
+	ImportPhaseUploading    ImportPhase = "uploading"
+	ImportPhaseParsing      ImportPhase = "parsing"
+	ImportPhaseCopying      ImportPhase = "copying"
+	ImportPhaseUpserting    ImportPhase = "upserting"
+	ImportPhaseDeleting     ImportPhase = "deleting"
+	ImportPhaseBrowseFields ImportPhase = "updating-browse-fields"
+	ImportPhaseFinished     ImportPhase = "finished"
+	ImportPhaseFailed       ImportPhase = "failed"
+)
+
+// progress percentage when each phase starts. copying moves between its
+// starting value and the next phase's as rows are sent to the db.
+var importPhaseProgress = []struct {
+	Phase ImportPhase
+	Start int
+}{
+	{ImportPhaseQueued, 0},
+	{ImportPhaseUploading, 5},
+	{ImportPhaseParsing, 20},
+	{ImportPhaseCopying, 35},
+	{ImportPhaseUpserting, 70},
+	{ImportPhaseDeleting, 85},
+	{ImportPhaseBrowseFields, 95},
+	{ImportPhaseFinished, 100},
+}
+
+// ImportProgressFunc is called as a long-running import moves along. progress is
+// how far through the given phase we are, from 0 to 1.
+type ImportProgressFunc func(phase ImportPhase, progress float64)
+
+type ImportJobResult struct {
+	TotalEntries int      `json:"total_entries"`
+	Nits         []string `json:"nits"`
+}
+
+type ImportJob struct {
+	ID         string             `json:"id"`
+	Filename   string             `json:"filename"`
+	Mode       ImportMode         `json:"mode"`
+	Options    ReadEntriesOptions `json:"options"`
+	Phase      ImportPhase        `json:"phase"`
+	Progress   int                `json:"progress"`
+	Result     *ImportJobResult   `json:"result,omitempty"`
+	Error      string             `json:"error,omitempty"`
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+}
+
+func (job *ImportJob) Done() bool {
+	return job.Phase == ImportPhaseFinished || job.Phase == ImportPhaseFailed
+}
+
+type ImportJobs struct {
+	sync.Mutex
+
+	jobs     map[string]*ImportJob
+	finished []string
+}
+
+var TheImportJobs = &ImportJobs{
+	jobs: make(map[string]*ImportJob),
+}
+
+// Get returns a copy of the given job.
+func (ij *ImportJobs) Get(id string) (job ImportJob, exists bool) {
+	ij.Lock()
+	defer ij.Unlock()
+
+	foundJob, exists := ij.jobs[id]
+	if exists {
+		job = *foundJob
+	}
+	return job, exists
+}
+
+// Start creates a new job. The caller should be holding the import lock.
+func (ij *ImportJobs) Start(filename string, mode ImportMode, options ReadEntriesOptions) (job ImportJob, err error) {
+	ij.Lock()
+	defer ij.Unlock()
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return job, err
+	}
+
+	newJob := &ImportJob{
+		ID:        id.String(),
+		Filename:  filename,
+		Mode:      mode,
+		Options:   options,
+		Phase:     ImportPhaseQueued,
+		StartedAt: time.Now().UTC(),
+	}
+	ij.jobs[newJob.ID] = newJob
+
+	return *newJob, nil
+}
+
+func (ij *ImportJobs) SetProgress(id string, phase ImportPhase, progress float64) {
+	ij.Lock()
+	defer ij.Unlock()
+
+	job, exists := ij.jobs[id]
+	if !exists || job.Done() {
+		return
+	}
+
+	var start, end int
+	for i, pp := range importPhaseProgress {
+		if pp.Phase == phase {
+			start = pp.Start
+			end = start
+			if i+1 < len(importPhaseProgress) {
+				end = importPhaseProgress[i+1].Start
+			}
+		}
+	}
+
+	job.Phase = phase
+	job.Progress = start + int(float64(end-start)*min(max(progress, 0), 1))
+}
+
+func (ij *ImportJobs) Finish(id string, result *ImportJobResult, err error) {
+	ij.Lock()
+	defer ij.Unlock()
+
+	job, exists := ij.jobs[id]
+	if !exists {
+		return
+	}
+
+	finishedAt := time.Now().UTC()
+	job.FinishedAt = &finishedAt
+	if err != nil {
+		job.Phase = ImportPhaseFailed
+		job.Error = err.Error()
+	} else {
+		job.Phase = ImportPhaseFinished
+		job.Progress = 100
+		job.Result = result
+	}
+
+	// forget about old jobs
+	ij.finished = append(ij.finished, id)
+	for len(ij.finished) > MaxFinishedImportJobs {
+		delete(ij.jobs, ij.finished[0])
+		ij.finished = ij.finished[1:]
+	}
+}
+
+// parseReadEntriesOptions reads the import options from the query string.
+func parseReadEntriesOptions(c *gin.Context) (options ReadEntriesOptions) {
+	symmetriseRaw, exists := c.GetQuery("symmetrise")
+	options.SymmetriseLinks = exists && symmetriseRaw == "true"
+	return options
+}
+
+// getImportLookups returns what ReadEntriesFile should check the sheet against
+// for an import in the given mode.
+func getImportLookups(mode ImportMode, existingEntries map[string]Entry) (lookups ImportLookups, err error) {
+	// only replace imports need the sheet to stand on its own
+	if mode != ImportModeReplace {
+		lookups.Entries = existingEntries
+	}
+
+	lookups.OrgNames, err = TheDb.GetOrgNameLookup()
+	if err != nil {
+		return lookups, fmt.Errorf("could not get organisations: %w", err)
+	}
+
+	lookups.VocabularyRules, err = TheDb.GetVocabularyRules()
+	if err != nil {
+		return lookups, fmt.Errorf("could not get vocabulary rules: %w", err)
+	}
+
+	relationTypes, err := TheDb.GetRelationTypes()
+	if err != nil {
+		return lookups, fmt.Errorf("could not get relationship types: %w", err)
+	}
+	lookups.RelationTypes = make(map[string]RelationType)
+	for _, relationType := range relationTypes {
+		lookups.RelationTypes[relationType.Name] = relationType
+	}
+
+	lookups.KeywordTerms, err = TheDb.GetKeywordTermLookup()
+	if err != nil {
+		return lookups, fmt.Errorf("could not get keywords: %w", err)
+	}
+
+	lookups.KnownValues, err = TheDb.GetKnownVocabulary()
+	if err != nil {
+		return lookups, fmt.Errorf("could not get existing vocabulary: %w", err)
+	}
+
+	return lookups, nil
+}
+
+// runImportJob does the actual import. It's run in its own goroutine so it
+// continues even if the client that started it goes away. The lock is
+// released once the job is finished.
+func runImportJob(jobID string, lock *ImportLock, s3fn string, buf *bytes.Buffer) {
+	defer lock.Release()
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("Import job panicked:", r)
+			TheImportJobs.Finish(jobID, nil, fmt.Errorf("import failed unexpectedly: %v", r))
+		}
+	}()
+
+	progress := func(phase ImportPhase, progress float64) {
+		TheImportJobs.SetProgress(jobID, phase, progress)
+	}
+
+	progress(ImportPhaseUploading, 0)
+	err := TheDb.UploadDbFile(s3fn, bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		fmt.Println("Could not upload new db file:", err.Error())
+		TheImportJobs.Finish(jobID, nil, fmt.Errorf("could not upload 'db' file: %w", err))
+		return
+	}
+
+	job, _ := TheImportJobs.Get(jobID)
+
+	progress(ImportPhaseParsing, 0)
+	var existingEntries map[string]Entry
+	if job.Mode != ImportModeReplace {
+		existingEntries, err = TheDb.GetAllEntries()
+		if err != nil {
+			fmt.Println("Could not get existing entries:", err.Error())
+			TheImportJobs.Finish(jobID, nil, err)
+			return
+		}
+	}
+
+	lookups, err := getImportLookups(job.Mode, existingEntries)
+	if err != nil {
+		fmt.Println("Could not get import lookups:", err.Error())
+		TheImportJobs.Finish(jobID, nil, err)
+		return
+	}
+
+	newEntries, err := ReadEntriesFile(bytes.NewReader(buf.Bytes()), lookups, job.Options)
+	if err != nil {
+		fmt.Println("Could not read entries file:", err.Error())
+		TheImportJobs.Finish(jobID, nil, err)
+		return
+	}
+
+	err = TheDb.UploadEntries(newEntries.Entries, job.Mode, EntryChange{
+		Source: "import of " + job.Filename,
+		Actor:  lock.Holder.Holder,
+	}, progress)
+	if err != nil {
+		fmt.Println("Could not upload entries:", err.Error())
+		TheImportJobs.Finish(jobID, nil, err)
+		return
+	}
+
+	Log(LogLevelInfo, "database-update", "Applied database update", map[string]string{
+		"filename": job.Filename,
+		"mode":     string(job.Mode),
+		"job":      jobID,
+	})
+
+	TheImportJobs.Finish(jobID, &ImportJobResult{
+		TotalEntries: len(newEntries.Entries),
+		Nits:         newEntries.Nits,
+	}, nil)
+}
+
+// handler
+
+type GetImportJobRequest struct {
+	ID string `uri:"slug" binding:"required"`
+}
+
+func getImportJob(c *gin.Context) {
+	var req GetImportJobRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		fmt.Println("Could not get import job URI binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Job must be given"})
+		return
+	}
+
+	job, exists := TheImportJobs.Get(req.ID)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Could not find import job"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+type GetImportLockResponse struct {
+	Locked bool              `json:"locked"`
+	Holder *ImportLockHolder `json:"holder"`
+}
+
+func getImportLock(c *gin.Context) {
+	holder, err := TheDb.GetImportLockHolder()
+	if err != nil {
+		fmt.Println("Could not get import lock holder:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get import lock"})
+		return
+	}
+
+	c.JSON(http.StatusOK, GetImportLockResponse{
+		Locked: holder != nil,
+		Holder: holder,
+	})
+}
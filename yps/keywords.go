@@ -0,0 +1,303 @@
+package yps
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cleanKeywordTerm tidies up the given term, and confirms its name and synonyms
+// don't belong to any other term and that its broader term doesn't make a loop.
+func cleanKeywordTerm(term KeywordTerm, terms []KeywordTerm) (KeywordTerm, error) {
+	term.Name = strings.Join(strings.Fields(term.Name), " ")
+	if term.Name == "" {
+		return term, errors.New("keyword name must be given")
+	}
+	term.Description = strings.TrimSpace(term.Description)
+
+	seen := map[string]bool{
+		NormaliseVocabularyValue(term.Name): true,
+	}
+	var synonyms []string
+	for _, synonym := range term.Synonyms {
+		synonym = strings.Join(strings.Fields(synonym), " ")
+		if synonym == "" || seen[NormaliseVocabularyValue(synonym)] {
+			continue
+		}
+		seen[NormaliseVocabularyValue(synonym)] = true
+		synonyms = append(synonyms, synonym)
+	}
+	term.Synonyms = synonyms
+
+	broaderOf := make(map[int]*int)
+	for _, otherTerm := range terms {
+		broaderOf[otherTerm.ID] = otherTerm.BroaderID
+		if otherTerm.ID == term.ID {
+			continue
+		}
+		for _, otherName := range append([]string{otherTerm.Name}, otherTerm.Synonyms...) {
+			if seen[NormaliseVocabularyValue(otherName)] {
+				return term, fmt.Errorf("'%s' is already used by keyword %d (%s), merge them instead", otherName, otherTerm.ID, otherTerm.Name)
+			}
+		}
+	}
+
+	if term.BroaderID != nil {
+		if _, exists := broaderOf[*term.BroaderID]; !exists {
+			return term, fmt.Errorf("broader keyword %d does not exist", *term.BroaderID)
+		}
+		// walk up from the new broader term, we shouldn't find ourselves
+		steps := 0
+		for id := term.BroaderID; id != nil; id = broaderOf[*id] {
+			if (term.ID != 0 && *id == term.ID) || steps > len(terms) {
+				return term, errors.New("a keyword can't be narrower than itself")
+			}
+			steps += 1
+		}
+	}
+
+	return term, nil
+}
+
+// mergedBroaderID returns the broader term the target should have once the
+// source is merged into it. A target anywhere underneath the source takes the
+// source's place, since the source's narrower terms are moved underneath it.
+func mergedBroaderID(source, target KeywordTerm, broaderOf map[int]*int) (*int, error) {
+	steps := 0
+	for id := target.BroaderID; id != nil; id = broaderOf[*id] {
+		if *id == source.ID {
+			return source.BroaderID, nil
+		}
+		if steps > len(broaderOf) {
+			return nil, fmt.Errorf("the broader keywords of keyword %d loop back on themselves", target.ID)
+		}
+		steps += 1
+	}
+	return target.BroaderID, nil
+}
+
+// buildKeywordTree nests terms under their broader terms and works out how
+// many entries are tagged with each term or anything narrower than it.
+func buildKeywordTree(terms []KeywordTerm, entryIDs map[string][]string) []KeywordTerm {
+	exists := make(map[int]bool)
+	for _, term := range terms {
+		exists[term.ID] = true
+	}
+
+	narrowerOf := make(map[int][]KeywordTerm)
+	var roots []KeywordTerm
+	for _, term := range terms {
+		if term.BroaderID == nil || !exists[*term.BroaderID] {
+			roots = append(roots, term)
+		} else {
+			narrowerOf[*term.BroaderID] = append(narrowerOf[*term.BroaderID], term)
+		}
+	}
+
+	visited := make(map[int]bool)
+	var build func(term KeywordTerm) (KeywordTerm, map[string]bool)
+	build = func(term KeywordTerm) (KeywordTerm, map[string]bool) {
+		visited[term.ID] = true
+
+		tagged := make(map[string]bool)
+		for _, id := range entryIDs[term.Name] {
+			tagged[id] = true
+		}
+
+		term.Narrower = []KeywordTerm{}
+		for _, narrowerTerm := range narrowerOf[term.ID] {
+			if visited[narrowerTerm.ID] {
+				continue
+			}
+			narrowerTerm, narrowerTagged := build(narrowerTerm)
+			term.Narrower = append(term.Narrower, narrowerTerm)
+			for id := range narrowerTagged {
+				tagged[id] = true
+			}
+		}
+		term.TotalEntries = len(tagged)
+
+		return term, tagged
+	}
+
+	tree := []KeywordTerm{}
+	for _, term := range roots {
+		term, _ = build(term)
+		tree = append(tree, term)
+	}
+
+	return tree
+}
+
+// handler
+
+type GetKeywordsResponse struct {
+	Keywords []KeywordTerm `json:"keywords"`
+}
+
+func getKeywords(c *gin.Context) {
+	isAdmin := isAdminRequest(c)
+	terms, err := TheDb.GetKeywordTerms(isAdmin)
+	if err != nil {
+		fmt.Println("Could not get keyword terms:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get keywords"})
+		return
+	}
+
+	entryIDs, err := TheDb.GetKeywordEntryIDs(isAdmin)
+	if err != nil {
+		fmt.Println("Could not get keyword entries:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get keywords"})
+		return
+	}
+
+	c.JSON(http.StatusOK, GetKeywordsResponse{
+		Keywords: buildKeywordTree(terms, entryIDs),
+	})
+}
+
+type KeywordTermRequest struct {
+	ID int `uri:"id" binding:"required"`
+}
+
+type EditKeywordTermParams struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Synonyms    []string `json:"synonyms"`
+	BroaderID   *int     `json:"broader_id"`
+}
+
+func saveKeywordTermFromParams(c *gin.Context, id int) {
+	var params EditKeywordTermParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	terms, err := TheDb.GetKeywordTerms(true)
+	if err != nil {
+		fmt.Println("Could not get keyword terms:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get keywords"})
+		return
+	}
+
+	term, err := cleanKeywordTerm(KeywordTerm{
+		ID:          id,
+		Name:        params.Name,
+		Description: params.Description,
+		Synonyms:    params.Synonyms,
+		BroaderID:   params.BroaderID,
+	}, terms)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	// renamed terms keep their old name as a synonym, so later imports still match it
+	for _, oldTerm := range terms {
+		if oldTerm.ID == id && NormaliseVocabularyValue(oldTerm.Name) != NormaliseVocabularyValue(term.Name) {
+			term.Synonyms = append(term.Synonyms, oldTerm.Name)
+		}
+	}
+
+	term.ID, err = TheDb.SaveKeywordTerm(term, EntryChange{
+		Source: "keyword edit of " + term.Name,
+		Actor:  requestActor(c),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Keyword not found"})
+		return
+	} else if err != nil {
+		fmt.Println("Could not save keyword term:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not save keyword"})
+		return
+	}
+
+	Log(LogLevelInfo, "keyword-update", "Updated keyword "+term.Name, term)
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "keyword": term})
+}
+
+func createKeywordTerm(c *gin.Context) {
+	saveKeywordTermFromParams(c, 0)
+}
+
+func editKeywordTerm(c *gin.Context) {
+	var req KeywordTermRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		fmt.Println("Could not get keyword URI binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Keyword must be given"})
+		return
+	}
+
+	saveKeywordTermFromParams(c, req.ID)
+}
+
+type MergeKeywordTermParams struct {
+	Into int `json:"into" binding:"required"`
+}
+
+func mergeKeywordTerm(c *gin.Context) {
+	var req KeywordTermRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		fmt.Println("Could not get keyword URI binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Keyword must be given"})
+		return
+	}
+
+	var params MergeKeywordTermParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if params.Into == req.ID {
+		c.JSON(400, gin.H{"error": "Can't merge a keyword into itself"})
+		return
+	}
+
+	err := TheDb.MergeKeywordTerms(req.ID, params.Into, EntryChange{
+		Source: fmt.Sprintf("keyword merge of %d into %d", req.ID, params.Into),
+		Actor:  requestActor(c),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Keyword not found"})
+		return
+	} else if err != nil {
+		fmt.Println("Could not merge keyword terms:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not merge keywords"})
+		return
+	}
+
+	Log(LogLevelInfo, "keyword-merge", fmt.Sprintf("Merged keyword %d into %d", req.ID, params.Into), map[string]int{
+		"from": req.ID,
+		"into": params.Into,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func deleteKeywordTerm(c *gin.Context) {
+	var req KeywordTermRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		fmt.Println("Could not get keyword URI binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Keyword must be given"})
+		return
+	}
+
+	err := TheDb.DeleteKeywordTerm(req.ID)
+	if err != nil {
+		fmt.Println("Could not delete keyword term:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not delete keyword"})
+		return
+	}
+
+	Log(LogLevelInfo, "keyword-delete", fmt.Sprintf("Deleted keyword %d", req.ID), map[string]int{
+		"keyword": req.ID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
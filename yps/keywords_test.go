@@ -0,0 +1,71 @@
+package yps
+
+import "testing"
+
+// countKeywordTree counts the terms in the tree, including narrower ones.
+func countKeywordTree(terms []KeywordTerm) (count int) {
+	for _, term := range terms {
+		count += 1 + countKeywordTree(term.Narrower)
+	}
+	return count
+}
+
+func TestMergedBroaderID(t *testing.T) {
+	id := func(i int) *int { return &i }
+
+	tests := []struct {
+		name string
+		// broader term of each term, before the merge
+		broaderOf map[int]*int
+		source    int
+		target    int
+		want      *int
+	}{
+		{"unrelated", map[int]*int{1: nil, 2: nil, 3: id(1)}, 1, 2, nil},
+		{"target keeps its broader term", map[int]*int{1: nil, 2: id(4), 3: id(1), 4: nil}, 1, 2, id(4)},
+		{"child", map[int]*int{1: id(5), 2: id(1), 5: nil}, 1, 2, id(5)},
+		{"grandchild", map[int]*int{1: nil, 2: id(1), 3: id(2)}, 1, 3, nil},
+		{"grandchild of nested source", map[int]*int{5: nil, 1: id(5), 2: id(1), 3: id(2)}, 1, 3, id(5)},
+	}
+
+	for _, test := range tests {
+		source := KeywordTerm{ID: test.source, BroaderID: test.broaderOf[test.source]}
+		target := KeywordTerm{ID: test.target, BroaderID: test.broaderOf[test.target]}
+		got, err := mergedBroaderID(source, target, test.broaderOf)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if (got == nil) != (test.want == nil) || (got != nil && *got != *test.want) {
+			t.Errorf("%s: broader ID is %v, expected %v", test.name, got, test.want)
+			continue
+		}
+
+		// do the rest of the merge the way MergeKeywordTerms does, and make
+		// sure every remaining term is still in the tree
+		var terms []KeywordTerm
+		for termID, broaderID := range test.broaderOf {
+			if termID == test.source {
+				continue
+			}
+			if termID == test.target {
+				broaderID = got
+			} else if broaderID != nil && *broaderID == test.source {
+				broaderID = id(test.target)
+			}
+			terms = append(terms, KeywordTerm{ID: termID, BroaderID: broaderID})
+		}
+		if count := countKeywordTree(buildKeywordTree(terms, nil)); count != len(terms) {
+			t.Errorf("%s: only %d of %d terms are in the tree after merging", test.name, count, len(terms))
+		}
+	}
+}
+
+func TestMergedBroaderIDLoop(t *testing.T) {
+	one, two := 1, 2
+	broaderOf := map[int]*int{1: &two, 2: &one, 3: nil}
+	_, err := mergedBroaderID(KeywordTerm{ID: 3}, KeywordTerm{ID: 1, BroaderID: &two}, broaderOf)
+	if err == nil {
+		t.Error("expected an error for broader terms that loop")
+	}
+}
@@ -0,0 +1,146 @@
+package ypsl
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// texts with fewer letters than this don't say enough to detect reliably
+const minDetectionLetters = 20
+
+// languages written in their own script are told apart by script alone
+var scriptLanguages = []struct {
+	code  string
+	table *unicode.RangeTable
+}{
+	{"ar", unicode.Arabic},
+	{"ru", unicode.Cyrillic},
+	{"zh", unicode.Han},
+}
+
+var (
+	detectionProfiles     map[string]map[string]float64
+	detectionProfilesOnce sync.Once
+)
+
+// ngrams counts the two and three letter sequences in each word of the text,
+// with words padded by spaces so that starts and ends of words count too.
+// single letters are left out, since most languages use them in much the same
+// proportions.
+func ngrams(text string) map[string]float64 {
+	counts := make(map[string]float64)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, word := range words {
+		padded := []rune(" " + word + " ")
+		for size := 2; size <= 3; size++ {
+			for i := 0; i+size <= len(padded); i++ {
+				counts[string(padded[i:i+size])]++
+			}
+		}
+	}
+	return counts
+}
+
+// normalise scales the counts so that the vector has a length of 1.
+func normalise(counts map[string]float64) map[string]float64 {
+	var sum float64
+	for _, count := range counts {
+		sum += count * count
+	}
+	length := math.Sqrt(sum)
+	for gram := range counts {
+		counts[gram] /= length
+	}
+	return counts
+}
+
+func loadDetectionProfiles() {
+	detectionProfiles = make(map[string]map[string]float64)
+	for code, sample := range detectionSamples {
+		detectionProfiles[code] = normalise(ngrams(sample))
+	}
+}
+
+// Detectable returns whether Detect can recognise the given language.
+func Detectable(code string) bool {
+	if _, exists := detectionSamples[code]; exists {
+		return true
+	}
+	for _, script := range scriptLanguages {
+		if script.code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Scores rates how much the text looks like each language that can be
+// detected, from 0 to 1. It's nil if the text is too short to tell. Text that's
+// mostly in a script of its own only scores for that script's language.
+func Scores(text string) map[string]float64 {
+	var letters int
+	scriptLetters := make([]int, len(scriptLanguages))
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for i, script := range scriptLanguages {
+			if unicode.Is(script.table, r) {
+				scriptLetters[i]++
+			}
+		}
+	}
+	for i, script := range scriptLanguages {
+		// each character of these carries more than a latin letter does
+		if scriptLetters[i] >= minDetectionLetters/4 && scriptLetters[i]*2 > letters {
+			return map[string]float64{
+				script.code: float64(scriptLetters[i]) / float64(letters),
+			}
+		}
+	}
+	if letters < minDetectionLetters {
+		return nil
+	}
+
+	detectionProfilesOnce.Do(loadDetectionProfiles)
+
+	// cosine similarity against each profile
+	scores := make(map[string]float64)
+	textNgrams := normalise(ngrams(text))
+	for code, profile := range detectionProfiles {
+		var similarity float64
+		for gram, weight := range textNgrams {
+			similarity += weight * profile[gram]
+		}
+		scores[code] = similarity
+	}
+	return scores
+}
+
+// Detect works out which language the text is in, and how confident it is
+// from 0 to 1, based on how far ahead of the next closest language it is.
+// code is blank if the text is too short to tell. Only the languages in
+// detectionSamples and scriptLanguages are recognised, so text in any other
+// language comes back as whichever of those is closest, with a low confidence.
+func Detect(text string) (code string, confidence float64) {
+	var best, second float64
+	for candidate, score := range Scores(text) {
+		if score > best {
+			second = best
+			best = score
+			code = candidate
+		} else if score > second {
+			second = score
+		}
+	}
+	if best == 0 {
+		return "", 0
+	}
+
+	return code, (best - second) / best
+}
@@ -0,0 +1,54 @@
+package ypsl
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"english", "Meaningful youth participation in peace negotiations and local governance", "en"},
+		{"spanish", "La participación significativa de la juventud en las negociaciones de paz y el gobierno local", "es"},
+		{"french", "La participation significative des jeunes aux négociations de paix et à la gouvernance locale", "fr"},
+		{"portuguese", "A participação significativa dos jovens nas negociações de paz e na governação local", "pt"},
+		{"german", "Die sinnvolle Beteiligung junger Menschen an Friedensverhandlungen und an der lokalen Verwaltung", "de"},
+		{"italian", "La partecipazione significativa dei giovani ai negoziati di pace e al governo locale", "it"},
+		{"arabic", "مشاركة الشباب في بناء السلام والأمن", "ar"},
+		{"russian", "Участие молодежи в миростроительстве и безопасности", "ru"},
+		{"chinese", "青年参与建设和平与安全", "zh"},
+		{"too short", "Youth and peace", ""},
+		{"blank", "", ""},
+		{"no letters", "2019 - 2021 / 123", ""},
+	}
+
+	for _, test := range tests {
+		got, confidence := Detect(test.text)
+		if got != test.want {
+			t.Errorf("%s: detected %q (confidence %.2f), expected %q", test.name, got, confidence, test.want)
+		}
+		if got == "" && confidence != 0 {
+			t.Errorf("%s: nothing detected, but confidence is %.2f", test.name, confidence)
+		}
+	}
+}
+
+func TestDetectable(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"en", true},
+		{"es", true},
+		{"ar", true},
+		{"zh", true},
+		{"sw", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if got := Detectable(test.code); got != test.want {
+			t.Errorf("%q: detectable is %v, expected %v", test.code, got, test.want)
+		}
+	}
+}
@@ -165,3 +165,10 @@ func GetName(code string) string {
 	}
 	return "Unknown"
 }
+
+func Names() (names []string) {
+	for _, value := range languageCodeMap {
+		names = append(names, value[0])
+	}
+	return names
+}
@@ -0,0 +1,98 @@
+package ypsl
+
+// detectionSamples are the texts each language's n-gram profile is built
+// from. They're written in the style of the entries we hold, so that common
+// domain words weigh in as well as the language's general shape.
+var detectionSamples = map[string]string{
+	"en": `Young people are often seen only as victims or perpetrators of violence, but they are also leaders
+in building peace in their communities. This report looks at how youth organisations have taken part in
+peace processes, and what governments and international organisations can do to support them. It draws
+on interviews with young peacebuilders from across the world, and finds that their work is too often
+underfunded and overlooked. The resolution on youth, peace and security recognises the important and
+positive contribution of young women and men to the maintenance of international peace and security.
+The study recommends that states should include young people in decision making at all levels, invest
+in their skills and participation, and protect civic space so that they can organise without fear. We
+also examine the barriers that young people face when they try to engage with formal institutions, such
+as age limits for public office, a lack of trust, and the costs of travelling to meetings. The findings
+show that when youth are given meaningful roles, agreements are more likely to last. This toolkit is
+for practitioners who want to design programmes with and for young people, and it includes practical
+guidance, case studies and a checklist for monitoring and evaluation. Which of these approaches works
+best depends on the context, and there is no single answer that would fit every country or region.`,
+
+	"es": `Las personas jóvenes suelen ser vistas solo como víctimas o autores de la violencia, pero también son
+líderes en la construcción de la paz en sus comunidades. Este informe analiza cómo las organizaciones
+juveniles han participado en los procesos de paz, y qué pueden hacer los gobiernos y los organismos
+internacionales para apoyarlas. Se basa en entrevistas con jóvenes constructores de paz de todo el mundo,
+y concluye que su trabajo a menudo carece de financiación y de reconocimiento. La resolución sobre
+juventud, paz y seguridad reconoce la contribución importante y positiva de las mujeres y los hombres
+jóvenes al mantenimiento de la paz y la seguridad internacionales. El estudio recomienda que los estados
+incluyan a la juventud en la toma de decisiones en todos los niveles, inviertan en sus capacidades y su
+participación, y protejan el espacio cívico para que puedan organizarse sin miedo. También examinamos los
+obstáculos que enfrentan los jóvenes cuando intentan relacionarse con las instituciones, como los límites
+de edad para cargos públicos, la falta de confianza y el costo de viajar a las reuniones. Los resultados
+muestran que cuando la juventud tiene un papel significativo, los acuerdos duran más. Esta guía está
+dirigida a quienes quieren diseñar programas con y para las personas jóvenes, e incluye orientaciones
+prácticas, estudios de caso y una lista de verificación para el seguimiento y la evaluación.`,
+
+	"fr": `Les jeunes sont souvent considérés uniquement comme des victimes ou des auteurs de violence, mais ils
+sont aussi des acteurs de la consolidation de la paix dans leurs communautés. Ce rapport examine la façon
+dont les organisations de jeunesse ont participé aux processus de paix, et ce que les gouvernements et les
+organisations internationales peuvent faire pour les soutenir. Il s'appuie sur des entretiens avec de
+jeunes bâtisseurs de paix du monde entier, et constate que leur travail est trop souvent sous-financé et
+ignoré. La résolution sur la jeunesse, la paix et la sécurité reconnaît la contribution importante et
+positive des jeunes femmes et des jeunes hommes au maintien de la paix et de la sécurité internationales.
+L'étude recommande que les États incluent les jeunes dans la prise de décision à tous les niveaux,
+investissent dans leurs compétences et leur participation, et protègent l'espace civique afin qu'ils
+puissent s'organiser sans crainte. Nous examinons également les obstacles auxquels les jeunes sont
+confrontés lorsqu'ils essaient de s'engager auprès des institutions, comme les limites d'âge pour les
+fonctions publiques, le manque de confiance et le coût des déplacements. Les résultats montrent que
+lorsque les jeunes ont un rôle réel, les accords sont plus durables. Ce guide s'adresse aux praticiens qui
+souhaitent concevoir des programmes avec et pour les jeunes, et comprend des conseils pratiques, des
+études de cas et une liste de contrôle pour le suivi et l'évaluation.`,
+
+	"pt": `Os jovens são muitas vezes vistos apenas como vítimas ou autores de violência, mas também são líderes
+na construção da paz nas suas comunidades. Este relatório analisa como as organizações de juventude têm
+participado nos processos de paz, e o que os governos e as organizações internacionais podem fazer para
+apoiá-las. Baseia-se em entrevistas com jovens construtores de paz de todo o mundo, e conclui que o seu
+trabalho é frequentemente subfinanciado e esquecido. A resolução sobre juventude, paz e segurança reconhece
+a contribuição importante e positiva das mulheres e dos homens jovens para a manutenção da paz e da
+segurança internacionais. O estudo recomenda que os estados incluam os jovens na tomada de decisões em
+todos os níveis, invistam nas suas competências e na sua participação, e protejam o espaço cívico para
+que possam organizar-se sem medo. Também examinamos as barreiras que os jovens enfrentam quando tentam
+envolver-se com as instituições, como os limites de idade para cargos públicos, a falta de confiança e o
+custo das viagens para as reuniões. Os resultados mostram que, quando a juventude tem um papel
+significativo, os acordos são mais duradouros. Este guia destina-se a quem quer desenhar programas com e
+para os jovens, e inclui orientações práticas, estudos de caso e uma lista de verificação para o
+acompanhamento e a avaliação. Não existe uma única resposta que sirva para todos os países ou regiões.`,
+
+	"de": `Junge Menschen werden oft nur als Opfer oder Täter von Gewalt gesehen, aber sie sind auch treibende
+Kräfte bei der Friedensförderung in ihren Gemeinschaften. Dieser Bericht untersucht, wie sich
+Jugendorganisationen an Friedensprozessen beteiligt haben und was Regierungen und internationale
+Organisationen tun können, um sie zu unterstützen. Er stützt sich auf Interviews mit jungen
+Friedensstifterinnen und Friedensstiftern aus der ganzen Welt und stellt fest, dass ihre Arbeit zu oft
+unterfinanziert und übersehen wird. Die Resolution über Jugend, Frieden und Sicherheit erkennt den
+wichtigen und positiven Beitrag junger Frauen und Männer zur Wahrung des Weltfriedens und der
+internationalen Sicherheit an. Die Studie empfiehlt, dass Staaten junge Menschen auf allen Ebenen in
+Entscheidungen einbeziehen, in ihre Fähigkeiten und ihre Teilhabe investieren und den zivilen Raum
+schützen, damit sie sich ohne Angst organisieren können. Außerdem betrachten wir die Hürden, denen junge
+Menschen begegnen, wenn sie sich bei staatlichen Einrichtungen engagieren wollen, etwa Altersgrenzen für
+öffentliche Ämter, fehlendes Vertrauen und die Kosten für die Anreise zu Sitzungen. Die Ergebnisse
+zeigen, dass Vereinbarungen länger halten, wenn die Jugend eine echte Rolle spielt. Dieser Leitfaden
+richtet sich an Fachleute, die Programme mit und für junge Menschen gestalten möchten, und enthält
+praktische Hinweise, Fallstudien und eine Checkliste für die Überwachung und Bewertung.`,
+
+	"it": `I giovani sono spesso visti soltanto come vittime o autori di violenza, ma sono anche protagonisti
+della costruzione della pace nelle loro comunità. Questo rapporto esamina come le organizzazioni
+giovanili abbiano partecipato ai processi di pace, e che cosa possano fare i governi e le organizzazioni
+internazionali per sostenerle. Si basa su interviste con giovani costruttori di pace di tutto il mondo,
+e conclude che il loro lavoro è troppo spesso sottofinanziato e trascurato. La risoluzione su gioventù,
+pace e sicurezza riconosce il contributo importante e positivo delle giovani donne e dei giovani uomini
+al mantenimento della pace e della sicurezza internazionali. Lo studio raccomanda che gli stati includano
+i giovani nei processi decisionali a tutti i livelli, investano nelle loro competenze e nella loro
+partecipazione, e proteggano lo spazio civico affinché possano organizzarsi senza paura. Esaminiamo
+inoltre gli ostacoli che i giovani incontrano quando cercano di impegnarsi con le istituzioni, come i
+limiti di età per le cariche pubbliche, la mancanza di fiducia e il costo degli spostamenti per le
+riunioni. I risultati mostrano che, quando i giovani hanno un ruolo significativo, gli accordi durano di
+più. Questa guida si rivolge a chi vuole progettare programmi con e per i giovani, e comprende
+indicazioni pratiche, studi di caso e una lista di controllo per il monitoraggio e la valutazione.`,
+}
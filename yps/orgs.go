@@ -0,0 +1,195 @@
+package yps
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NormaliseOrgName returns the form organisation names and aliases are
+// matched in, so 'UNFPA ' and 'unfpa' are the same organisation.
+func NormaliseOrgName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// cleanOrg tidies up the given organisation and confirms its name and aliases
+// don't belong to any other organisation.
+func cleanOrg(org Organisation) (Organisation, error) {
+	org.Name = strings.Join(strings.Fields(org.Name), " ")
+	if org.Name == "" {
+		return org, errors.New("organisation name must be given")
+	}
+	org.OrgType = strings.TrimSpace(org.OrgType)
+	org.Country = strings.TrimSpace(org.Country)
+	org.Website = strings.TrimSpace(org.Website)
+
+	seen := map[string]bool{
+		NormaliseOrgName(org.Name): true,
+	}
+	var aliases []string
+	for _, alias := range org.Aliases {
+		alias = strings.Join(strings.Fields(alias), " ")
+		if alias == "" || seen[NormaliseOrgName(alias)] {
+			continue
+		}
+		seen[NormaliseOrgName(alias)] = true
+		aliases = append(aliases, alias)
+	}
+	org.Aliases = aliases
+
+	orgs, err := TheDb.GetOrgs(true)
+	if err != nil {
+		return org, err
+	}
+	for _, otherOrg := range orgs {
+		if otherOrg.ID == org.ID {
+			continue
+		}
+		for _, otherName := range append([]string{otherOrg.Name}, otherOrg.Aliases...) {
+			if seen[NormaliseOrgName(otherName)] {
+				return org, fmt.Errorf("'%s' is already used by organisation %d (%s), remove it from there first", otherName, otherOrg.ID, otherOrg.Name)
+			}
+		}
+	}
+
+	return org, nil
+}
+
+// handler
+
+type GetOrgsResponse struct {
+	Orgs []Organisation `json:"orgs"`
+}
+
+func getOrgs(c *gin.Context) {
+	orgs, err := TheDb.GetOrgs(isAdminRequest(c))
+	if err != nil {
+		fmt.Println("Could not get organisations:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get organisations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, GetOrgsResponse{
+		Orgs: orgs,
+	})
+}
+
+type OrgRequest struct {
+	ID int `uri:"id" binding:"required"`
+}
+
+type GetOrgResponse struct {
+	Org     Organisation  `json:"org"`
+	Entries []SearchEntry `json:"entries"`
+}
+
+func getOrg(c *gin.Context) {
+	var req OrgRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		fmt.Println("Could not get organisation URI binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Organisation must be given"})
+		return
+	}
+
+	org, entries, err := TheDb.GetOrg(req.ID, isAdminRequest(c))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organisation not found"})
+		return
+	} else if err != nil {
+		fmt.Println("Could not get organisation:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get organisation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, GetOrgResponse{
+		Org:     org,
+		Entries: entries,
+	})
+}
+
+type EditOrgParams struct {
+	Name    string   `json:"name" binding:"required"`
+	Aliases []string `json:"aliases"`
+	OrgType string   `json:"org_type"`
+	Country string   `json:"country"`
+	Website string   `json:"website"`
+}
+
+func saveOrgFromParams(c *gin.Context, id int) {
+	var params EditOrgParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	org, err := cleanOrg(Organisation{
+		ID:      id,
+		Name:    params.Name,
+		Aliases: params.Aliases,
+		OrgType: params.OrgType,
+		Country: params.Country,
+		Website: params.Website,
+	})
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	org.ID, err = TheDb.SaveOrg(org, EntryChange{
+		Source: "organisation edit of " + org.Name,
+		Actor:  requestActor(c),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organisation not found"})
+		return
+	} else if err != nil {
+		fmt.Println("Could not save organisation:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not save organisation"})
+		return
+	}
+
+	Log(LogLevelInfo, "org-update", "Updated organisation "+org.Name, org)
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "org": org})
+}
+
+func createOrg(c *gin.Context) {
+	saveOrgFromParams(c, 0)
+}
+
+func editOrg(c *gin.Context) {
+	var req OrgRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		fmt.Println("Could not get organisation URI binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Organisation must be given"})
+		return
+	}
+
+	saveOrgFromParams(c, req.ID)
+}
+
+func deleteOrg(c *gin.Context) {
+	var req OrgRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		fmt.Println("Could not get organisation URI binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Organisation must be given"})
+		return
+	}
+
+	err := TheDb.DeleteOrg(req.ID)
+	if err != nil {
+		fmt.Println("Could not delete organisation:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not delete organisation"})
+		return
+	}
+
+	Log(LogLevelInfo, "org-delete", fmt.Sprintf("Deleted organisation %d", req.ID), map[string]int{
+		"org": req.ID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
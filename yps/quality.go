@@ -0,0 +1,207 @@
+package yps
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	ypsl "github.com/YPS-Database/yps-db-backend/yps/languages"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultQualityEntriesPerRule = 100
+
+// qualityContext is what rules can look at beyond the entry itself.
+type qualityContext struct {
+	Entries    map[string]Entry
+	FileCounts map[string]int
+}
+
+// QualityRule is a check for a common problem with entries. To add a check,
+// add a rule to qualityRules.
+type QualityRule struct {
+	Name        string
+	Description string
+	// Failing returns whether the entry has the problem
+	Failing func(entry Entry, ctx *qualityContext) bool
+	// Detail optionally describes the problem with a failing entry
+	Detail func(entry Entry, ctx *qualityContext) string
+}
+
+var qualityRules = []QualityRule{
+	{
+		Name:        "missing_abstract",
+		Description: "No abstract or executive summary",
+		Failing: func(entry Entry, ctx *qualityContext) bool {
+			return strings.TrimSpace(entry.Abstract) == ""
+		},
+	},
+	{
+		Name:        "youth_led_unknown",
+		Description: "Youth-led status couldn't be worked out",
+		Failing: func(entry Entry, ctx *qualityContext) bool {
+			return entry.YouthLed == "Unknown"
+		},
+	},
+	{
+		Name:        "region_only_na",
+		Description: "The only region is N/A",
+		Failing: func(entry Entry, ctx *qualityContext) bool {
+			return len(entry.Regions) == 1 && entry.Regions[0] == "N/A"
+		},
+	},
+	{
+		Name:        "no_files_or_url",
+		Description: "No files and no URL, so there's no way to get the document",
+		Failing: func(entry Entry, ctx *qualityContext) bool {
+			return ctx.FileCounts[entry.ItemID] == 0 && strings.TrimSpace(entry.URL) == ""
+		},
+	},
+	{
+		Name:        "start_date_jan_1",
+		Description: "Exact start date is 1 January, which is often a stand-in for an unknown day",
+		Failing: func(entry Entry, ctx *qualityContext) bool {
+			// month precision dates are always on the 1st, so only exact days count
+			return entry.DatePrecision == DatePrecisionDay &&
+				entry.StartDate.Month() == time.January && entry.StartDate.Day() == 1
+		},
+	},
+	{
+		Name:        "malformed_url",
+		Description: "URL isn't a full http or https link",
+		Failing: func(entry Entry, ctx *qualityContext) bool {
+			if strings.TrimSpace(entry.URL) == "" {
+				return false
+			}
+			parsed, err := url.Parse(strings.TrimSpace(entry.URL))
+			return err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || strings.ContainsAny(entry.URL, " \t\n")
+		},
+	},
+	{
+		Name:        "language_mismatch",
+		Description: "Title and abstract look like they're in a different language to the one the entry is marked as",
+		Failing: func(entry Entry, ctx *qualityContext) bool {
+			_, _, mismatch := detectLanguageMismatch(entry.Title, entry.Abstract, entry.Language)
+			return mismatch
+		},
+		Detail: func(entry Entry, ctx *qualityContext) string {
+			detected, confidence, _ := detectLanguageMismatch(entry.Title, entry.Abstract, entry.Language)
+			return fmt.Sprintf("Marked as %s, looks like %s (confidence %.2f)", ypsl.GetName(entry.Language), ypsl.GetName(detected), confidence)
+		},
+	},
+	{
+		Name:        "dangling_alternates",
+		Description: "Lists alternate language entries that don't exist",
+		Failing: func(entry Entry, ctx *qualityContext) bool {
+			return slices.ContainsFunc(entry.AltLanguageIDs, func(id string) bool {
+				_, exists := ctx.Entries[id]
+				return !exists
+			})
+		},
+	},
+}
+
+// handler
+
+type QualityRuleResult struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Count       int    `json:"count"`
+	// up to the requested limit, in item ID order
+	Entries []QualityEntry `json:"entries"`
+}
+
+type QualityEntry struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type GetQualityReportRequest struct {
+	Rule  string `form:"rule"`
+	Limit int    `form:"limit"`
+}
+
+type GetQualityReportResponse struct {
+	TotalEntries int                 `json:"total_entries"`
+	Rules        []QualityRuleResult `json:"rules"`
+}
+
+func getQualityReport(c *gin.Context) {
+	var req GetQualityReportRequest
+	if err := c.ShouldBind(&req); err != nil {
+		fmt.Println("Could not get quality report binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not read params"})
+		return
+	}
+	if req.Limit < 1 {
+		req.Limit = defaultQualityEntriesPerRule
+	}
+	if req.Rule != "" && !slices.ContainsFunc(qualityRules, func(rule QualityRule) bool {
+		return rule.Name == req.Rule
+	}) {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Rule %s does not exist", req.Rule)})
+		return
+	}
+
+	entries, err := TheDb.GetAllEntries()
+	if err != nil {
+		fmt.Println("Could not get entries:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get entries"})
+		return
+	}
+	fileCounts, err := TheDb.GetEntryFileCounts()
+	if err != nil {
+		fmt.Println("Could not get entry file counts:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get entry files"})
+		return
+	}
+	ctx := qualityContext{
+		Entries:    entries,
+		FileCounts: fileCounts,
+	}
+
+	var ids []string
+	for id := range entries {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, compareItemIDs)
+
+	response := GetQualityReportResponse{
+		TotalEntries: len(entries),
+		Rules:        []QualityRuleResult{},
+	}
+	for _, rule := range qualityRules {
+		if req.Rule != "" && rule.Name != req.Rule {
+			continue
+		}
+
+		result := QualityRuleResult{
+			Name:        rule.Name,
+			Description: rule.Description,
+			Entries:     []QualityEntry{},
+		}
+		for _, id := range ids {
+			if !rule.Failing(entries[id], &ctx) {
+				continue
+			}
+			result.Count++
+			if len(result.Entries) < req.Limit {
+				qualityEntry := QualityEntry{
+					ID:    id,
+					Title: entries[id].Title,
+				}
+				if rule.Detail != nil {
+					qualityEntry.Detail = rule.Detail(entries[id], &ctx)
+				}
+				result.Entries = append(result.Entries, qualityEntry)
+			}
+		}
+		response.Rules = append(response.Rules, result)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,140 @@
+package yps
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// items in the related column without a type, or with this one, are plain related links
+const plainRelationType = "related"
+
+// parseRelatedItem reads an item from the related column, which is either an
+// item ID or a typed link like 'supersedes:123'.
+func parseRelatedItem(item string) (relationType, id string) {
+	typeName, id, typed := strings.Cut(item, ":")
+	if !typed {
+		return plainRelationType, strings.TrimSpace(item)
+	}
+	relationType = strings.ReplaceAll(NormaliseVocabularyValue(typeName), " ", "-")
+	return relationType, strings.TrimSpace(id)
+}
+
+func sortRelations(relations []EntryRelation) {
+	slices.SortFunc(relations, func(a, b EntryRelation) int {
+		if a.Type != b.Type {
+			return strings.Compare(a.Type, b.Type)
+		}
+		return compareItemIDs(a.ID, b.ID)
+	})
+}
+
+func cleanRelationType(relationType RelationType) (RelationType, error) {
+	relationType.Name = strings.ReplaceAll(NormaliseVocabularyValue(relationType.Name), " ", "-")
+	relationType.Label = strings.TrimSpace(relationType.Label)
+	relationType.InverseLabel = strings.TrimSpace(relationType.InverseLabel)
+
+	if relationType.Name == "" || relationType.Label == "" {
+		return relationType, errors.New("name and label must be given")
+	}
+	if relationType.Name == plainRelationType || strings.Contains(relationType.Name, ":") {
+		return relationType, fmt.Errorf("[%s] can't be used as a relationship type name", relationType.Name)
+	}
+	if relationType.Symmetric || relationType.InverseLabel == "" {
+		relationType.InverseLabel = relationType.Label
+	}
+
+	return relationType, nil
+}
+
+// handler
+
+type GetRelationTypesResponse struct {
+	Types []RelationType `json:"types"`
+}
+
+func getRelationTypes(c *gin.Context) {
+	relationTypes, err := TheDb.GetRelationTypes()
+	if err != nil {
+		fmt.Println("Could not get relation types:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get relationship types"})
+		return
+	}
+
+	c.JSON(http.StatusOK, GetRelationTypesResponse{
+		Types: relationTypes,
+	})
+}
+
+type RelationTypeRequest struct {
+	Name string `uri:"slug" binding:"required"`
+}
+
+type EditRelationTypeParams struct {
+	Label        string `json:"label" binding:"required"`
+	InverseLabel string `json:"inverse_label"`
+	Symmetric    bool   `json:"symmetric"`
+}
+
+func editRelationType(c *gin.Context) {
+	var req RelationTypeRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		fmt.Println("Could not get relation type URI binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Relationship type must be given"})
+		return
+	}
+
+	var params EditRelationTypeParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	relationType, err := cleanRelationType(RelationType{
+		Name:         req.Name,
+		Label:        params.Label,
+		InverseLabel: params.InverseLabel,
+		Symmetric:    params.Symmetric,
+	})
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = TheDb.SaveRelationType(relationType)
+	if err != nil {
+		fmt.Println("Could not save relation type:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not save relationship type"})
+		return
+	}
+
+	Log(LogLevelInfo, "relation-type-update", "Updated relationship type "+relationType.Name, relationType)
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "type": relationType})
+}
+
+func deleteRelationType(c *gin.Context) {
+	var req RelationTypeRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		fmt.Println("Could not get relation type URI binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Relationship type must be given"})
+		return
+	}
+
+	err := TheDb.DeleteRelationType(req.Name)
+	if err != nil {
+		fmt.Println("Could not delete relation type:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not delete relationship type, check that no entries use it"})
+		return
+	}
+
+	Log(LogLevelInfo, "relation-type-delete", "Deleted relationship type "+req.Name, map[string]string{
+		"type": req.Name,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
@@ -0,0 +1,227 @@
+package yps
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	reportsPerHourLimit = 10
+	maxReportLength     = 5000
+)
+
+type EntryReportCategory string
+
+const (
+	EntryReportWrongDate    EntryReportCategory = "wrong_date"
+	EntryReportBrokenLink   EntryReportCategory = "broken_link"
+	EntryReportWrongRegion  EntryReportCategory = "wrong_region"
+	EntryReportWrongDetails EntryReportCategory = "wrong_details"
+	EntryReportDuplicate    EntryReportCategory = "duplicate"
+	EntryReportOther        EntryReportCategory = "other"
+)
+
+var entryReportCategories = []EntryReportCategory{
+	EntryReportWrongDate,
+	EntryReportBrokenLink,
+	EntryReportWrongRegion,
+	EntryReportWrongDetails,
+	EntryReportDuplicate,
+	EntryReportOther,
+}
+
+type EntryReportStatus string
+
+const (
+	EntryReportStatusOpen     EntryReportStatus = "open"
+	EntryReportStatusResolved EntryReportStatus = "resolved"
+	EntryReportStatusWontFix  EntryReportStatus = "wont_fix"
+)
+
+// the fields a report can point at, named as in EditEntryParams
+var reportableEntryFields = []string{
+	"title", "authors", "url", "orgs", "org_doc_id", "org_type", "entry_type", "abstract",
+	"youth_led_details", "keywords", "regions", "year", "day_month", "languages",
+	"alt_language_ids", "related", "files",
+}
+
+// EntryReport is a correction a reader has suggested for an entry.
+type EntryReport struct {
+	ID      int    `json:"id"`
+	EntryID string `json:"entry_id"`
+	// only filled in for the queue. the entry may have been deleted since
+	EntryTitle  string              `json:"entry_title"`
+	EntryExists bool                `json:"entry_exists"`
+	Category    EntryReportCategory `json:"category"`
+	// blank if the report isn't about a particular field
+	Field          string            `json:"field"`
+	Message        string            `json:"message"`
+	Status         EntryReportStatus `json:"status"`
+	ReportedAt     time.Time         `json:"reported_at"`
+	ResolvedAt     *time.Time        `json:"resolved_at"`
+	ResolvedBy     string            `json:"resolved_by"`
+	ResolutionNote string            `json:"resolution_note"`
+}
+
+// handler
+
+type CreateEntryReportParams struct {
+	Category EntryReportCategory `json:"category" binding:"required"`
+	Field    string              `json:"field"`
+	Message  string              `json:"message" binding:"required"`
+	// named after honeypotField
+	Honeypot string `json:"website"`
+}
+
+func createEntryReport(c *gin.Context) {
+	var req GetEntryRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		fmt.Println("Could not get entry URI binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Entry must be given"})
+		return
+	}
+
+	var params CreateEntryReportParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if rejectSpam(c, "report", params.Honeypot, TheDb.CountRecentEntryReports, reportsPerHourLimit) {
+		return
+	}
+
+	report := EntryReport{
+		EntryID:  req.ID,
+		Category: params.Category,
+		Field:    strings.TrimSpace(params.Field),
+		Message:  strings.TrimSpace(params.Message),
+	}
+	if !slices.Contains(entryReportCategories, report.Category) {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Category must be one of: %v", entryReportCategories)})
+		return
+	}
+	if report.Field != "" && !slices.Contains(reportableEntryFields, report.Field) {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Field must be one of: %s", strings.Join(reportableEntryFields, ", "))})
+		return
+	}
+	if report.Message == "" || utf8.RuneCountInString(report.Message) > maxReportLength {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Message must be given, and be at most %d characters", maxReportLength)})
+		return
+	}
+
+	exists, err := TheDb.PublicEntryExists(req.ID)
+	if err != nil {
+		fmt.Println("Could not check entry exists:", req.ID, ":", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get entry"})
+		return
+	} else if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Entry not found"})
+		return
+	}
+
+	report.ID, err = TheDb.CreateEntryReport(report, c.ClientIP())
+	if err != nil {
+		fmt.Println("Could not create report:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not accept report"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"ok": true, "id": report.ID})
+}
+
+type GetEntryReportsRequest struct {
+	Status  EntryReportStatus `form:"status"`
+	EntryID string            `form:"entry"`
+}
+
+type GetEntryReportsResponse struct {
+	Reports []EntryReport `json:"reports"`
+}
+
+func isEntryReportStatus(status EntryReportStatus) bool {
+	return slices.Contains([]EntryReportStatus{EntryReportStatusOpen, EntryReportStatusResolved, EntryReportStatusWontFix}, status)
+}
+
+// getEntryReports returns the triage queue, which defaults to the open reports.
+func getEntryReports(c *gin.Context) {
+	var req GetEntryReportsRequest
+	if err := c.ShouldBind(&req); err != nil {
+		fmt.Println("Could not get reports binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not read params"})
+		return
+	}
+	if req.Status == "" {
+		req.Status = EntryReportStatusOpen
+	} else if req.Status == "all" {
+		req.Status = ""
+	} else if !isEntryReportStatus(req.Status) {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("status must be one of: all, %s, %s, %s", EntryReportStatusOpen, EntryReportStatusResolved, EntryReportStatusWontFix)})
+		return
+	}
+
+	reports, err := TheDb.GetEntryReports(req.Status, strings.TrimSpace(req.EntryID))
+	if err != nil {
+		fmt.Println("Could not get reports:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, GetEntryReportsResponse{
+		Reports: reports,
+	})
+}
+
+type EntryReportRequest struct {
+	ID int `uri:"id" binding:"required"`
+}
+
+type EditEntryReportParams struct {
+	Status EntryReportStatus `json:"status" binding:"required"`
+	Note   string            `json:"note"`
+}
+
+func editEntryReport(c *gin.Context) {
+	var req EntryReportRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		fmt.Println("Could not get report URI binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Report must be given"})
+		return
+	}
+
+	var params EditEntryReportParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !isEntryReportStatus(params.Status) {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("status must be one of: %s, %s, %s", EntryReportStatusOpen, EntryReportStatusResolved, EntryReportStatusWontFix)})
+		return
+	}
+
+	report, err := TheDb.SetEntryReportStatus(req.ID, params.Status, requestActor(c), strings.TrimSpace(params.Note))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+		return
+	} else if err != nil {
+		fmt.Println("Could not update report:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not update report"})
+		return
+	}
+
+	// the whole report goes in the log, so there's a record of what was asked for
+	if report.Status == EntryReportStatusOpen {
+		Log(LogLevelInfo, "report-reopen", fmt.Sprintf("Reopened report %d on entry %s", report.ID, report.EntryID), report)
+	} else {
+		Log(LogLevelInfo, "report-resolve", fmt.Sprintf("Closed report %d on entry %s as %s", report.ID, report.EntryID, report.Status), report)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "report": report})
+}
@@ -0,0 +1,138 @@
+package yps
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// revisions store whole entries rows, these are the Entry fields that each column is shown as
+var revisionFieldNames = map[string]string{
+	"entry_type":          "entry_type",
+	"entry_language":      "language",
+	"alternates":          "alt_language_ids",
+	"related":             "related_ids",
+	"youth_led":           "youth_led_details",
+	"youth_led_distilled": "youth_led",
+}
+
+// diffEntryRevision lists the fields that differ between the old and new
+// values of a revision.
+func diffEntryRevision(oldValues, newValues map[string]any) (changes []EntryFieldChange) {
+	changes = []EntryFieldChange{}
+
+	var columns []string
+	for column := range oldValues {
+		columns = append(columns, column)
+	}
+	for column := range newValues {
+		if _, exists := oldValues[column]; !exists {
+			columns = append(columns, column)
+		}
+	}
+	slices.Sort(columns)
+
+	for _, column := range columns {
+		if column == "id" {
+			continue
+		}
+		oldValue, newValue := oldValues[column], newValues[column]
+		if oldValues != nil && newValues != nil && reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		field, renamed := revisionFieldNames[column]
+		if !renamed {
+			field = column
+		}
+		changes = append(changes, EntryFieldChange{
+			Field: field,
+			Old:   oldValue,
+			New:   newValue,
+		})
+	}
+
+	return changes
+}
+
+// handler
+
+type GetEntryHistoryResponse struct {
+	Revisions []EntryRevision `json:"revisions"`
+}
+
+func getEntryHistory(c *gin.Context) {
+	var req GetEntryRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		fmt.Println("Could not get entry URI binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Entry must be given"})
+		return
+	}
+
+	revisions, err := TheDb.GetEntryRevisions(req.ID)
+	if err != nil {
+		fmt.Println("Could not get entry revisions:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get entry history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, GetEntryHistoryResponse{
+		Revisions: revisions,
+	})
+}
+
+type RestoreEntryRevisionRequest struct {
+	ID       string `uri:"slug" binding:"required"`
+	Revision int    `uri:"id" binding:"required"`
+}
+
+func restoreEntryRevision(c *gin.Context) {
+	var req RestoreEntryRevisionRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		fmt.Println("Could not get entry revision URI binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Entry and revision must be given"})
+		return
+	}
+
+	lock := acquireImportLockOrConflict(c, "restore of entry "+req.ID)
+	if lock == nil {
+		return
+	}
+	defer lock.Release()
+
+	err := TheDb.RestoreEntryRevision(req.ID, req.Revision, EntryChange{
+		Source: fmt.Sprintf("restore of revision %d", req.Revision),
+		Actor:  requestActor(c),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	} else if err != nil {
+		fmt.Println("Could not restore entry revision:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not restore entry"})
+		return
+	}
+
+	err = UpdateBrowseByFields()
+	if err != nil {
+		fmt.Println("Could not update browse-by fields:", err.Error())
+	}
+
+	Log(LogLevelInfo, "entry-restore", fmt.Sprintf("Restored entry %s to revision %d", req.ID, req.Revision), map[string]any{
+		"entry":    req.ID,
+		"revision": req.Revision,
+	})
+
+	luEntry, err := TheDb.GetSingleEntry(req.ID, true)
+	if err != nil {
+		fmt.Println("Could not get entry:", req.ID, ":", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get entry"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "entry": luEntry.AsEntryResponse()})
+}
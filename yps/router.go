@@ -34,20 +34,68 @@ func GetRouter(trustedProxies []string, corsAllowedFrom []string) (router *gin.E
 
 	// DB
 	router.GET("/api/dbs", getYpsDbs)
-	router.GET("/api/db", getLatestYpsDb)
+	router.GET("/api/db", OptionalAuthMiddleware(), getLatestYpsDb)
+	router.GET("/api/db/template.xlsx", getYpsDbTemplate)
+	router.GET("/api/db/export.xlsx", AdminAuthMiddleware(), exportYpsDb)
 	router.PUT("/api/db", AdminAuthMiddleware(), updateYpsDb)
 	router.DELETE("/api/db/:slug", AdminAuthMiddleware(), deleteYpsDb)
 
+	// imports
+	router.GET("/api/imports/jobs/:slug", AdminAuthMiddleware(), getImportJob)
+	router.GET("/api/imports/lock", AdminAuthMiddleware(), getImportLock)
+
+	// vocabulary
+	router.GET("/api/vocabulary/rules", AdminAuthMiddleware(), getVocabularyRules)
+	router.POST("/api/vocabulary/rules", AdminAuthMiddleware(), createVocabularyRule)
+	router.PUT("/api/vocabulary/rules/:id", AdminAuthMiddleware(), editVocabularyRule)
+	router.DELETE("/api/vocabulary/rules/:id", AdminAuthMiddleware(), deleteVocabularyRule)
+
 	// pages
 	router.GET("/api/page/:slug", getPage)
 	router.PUT("/api/page/:slug", AdminAuthMiddleware(), editPage)
 
 	// entries
-	router.GET("/api/entry/:slug", getEntry)
+	router.GET("/api/entries", OptionalAuthMiddleware(), getEntries)
+	router.POST("/api/entries", OptionalAuthMiddleware(), getEntries)
+	router.POST("/api/entry", AdminAuthMiddleware(), createEntry)
+	router.GET("/api/entry/:slug", OptionalAuthMiddleware(), getEntry)
+	router.PATCH("/api/entry/:slug", AdminAuthMiddleware(), editEntry)
+	router.DELETE("/api/entry/:slug", AdminAuthMiddleware(), deleteEntry)
+	router.GET("/api/entry/:slug/similar", OptionalAuthMiddleware(), getSimilarEntries)
+	router.GET("/api/entry/:slug/history", AdminAuthMiddleware(), getEntryHistory)
+	router.POST("/api/entry/:slug/history/:id/restore", AdminAuthMiddleware(), restoreEntryRevision)
+	router.POST("/api/entry/:slug/reports", createEntryReport)
 	router.POST("/api/entry/:slug/file", AdminAuthMiddleware(), uploadEntryFile)
 	router.DELETE("/api/entry/:slug/file", AdminAuthMiddleware(), deleteEntryFile)
-	router.GET("/api/browseby", getBrowseByFields)
-	router.GET("/api/search", searchEntries)
+	router.GET("/api/duplicates", AdminAuthMiddleware(), getDuplicates)
+	router.POST("/api/duplicates/merge", AdminAuthMiddleware(), mergeEntries)
+	router.GET("/api/admin/quality", AdminAuthMiddleware(), getQualityReport)
+	router.GET("/api/admin/translations", AdminAuthMiddleware(), getTranslationCoverage)
+	router.GET("/api/browseby", OptionalAuthMiddleware(), getBrowseByFields)
+	router.GET("/api/authors", OptionalAuthMiddleware(), getAuthors)
+	router.GET("/api/relation-types", getRelationTypes)
+	router.PUT("/api/relation-types/:slug", AdminAuthMiddleware(), editRelationType)
+	router.DELETE("/api/relation-types/:slug", AdminAuthMiddleware(), deleteRelationType)
+	router.GET("/api/keywords", OptionalAuthMiddleware(), getKeywords)
+	router.POST("/api/keywords", AdminAuthMiddleware(), createKeywordTerm)
+	router.PUT("/api/keywords/:id", AdminAuthMiddleware(), editKeywordTerm)
+	router.POST("/api/keywords/:id/merge", AdminAuthMiddleware(), mergeKeywordTerm)
+	router.DELETE("/api/keywords/:id", AdminAuthMiddleware(), deleteKeywordTerm)
+	router.GET("/api/orgs", OptionalAuthMiddleware(), getOrgs)
+	router.POST("/api/orgs", AdminAuthMiddleware(), createOrg)
+	router.GET("/api/orgs/:id", OptionalAuthMiddleware(), getOrg)
+	router.PUT("/api/orgs/:id", AdminAuthMiddleware(), editOrg)
+	router.DELETE("/api/orgs/:id", AdminAuthMiddleware(), deleteOrg)
+	router.POST("/api/submissions", createSubmission)
+	router.GET("/api/submissions/status/:slug", getSubmissionStatus)
+	router.GET("/api/submissions", AdminAuthMiddleware(), getSubmissions)
+	router.GET("/api/submissions/:id", AdminAuthMiddleware(), getSubmission)
+	router.PUT("/api/submissions/:id", AdminAuthMiddleware(), editSubmission)
+	router.POST("/api/submissions/:id/approve", AdminAuthMiddleware(), approveSubmission)
+	router.POST("/api/submissions/:id/reject", AdminAuthMiddleware(), rejectSubmission)
+	router.GET("/api/reports", AdminAuthMiddleware(), getEntryReports)
+	router.PUT("/api/reports/:id", AdminAuthMiddleware(), editEntryReport)
+	router.GET("/api/search", OptionalAuthMiddleware(), searchEntries)
 	router.PUT("/api/import-files", AdminAuthMiddleware(), importFileList)
 
 	router.NoRoute(func(c *gin.Context) {
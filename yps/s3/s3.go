@@ -5,6 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -18,9 +21,18 @@ type YPSS3 struct {
 	bucket          string
 	uploadKeyPrefix string
 	uploadURLPrefix string
+	// files under this aren't served publicly, and are only shared with presigned URLs
+	privateKeyPrefix string
 }
 
-func Open(bucket, uploadKeyPrefix, uploadURLPrefix string) (*YPSS3, error) {
+// how long presigned URLs to private files work for
+const privateURLExpiry = time.Hour
+
+func Open(bucket, uploadKeyPrefix, uploadURLPrefix, privateKeyPrefix string) (*YPSS3, error) {
+	if strings.HasPrefix(privateKeyPrefix, uploadKeyPrefix) {
+		return nil, fmt.Errorf("private key prefix [%s] must not be inside the public upload key prefix [%s]", privateKeyPrefix, uploadKeyPrefix)
+	}
+
 	// Load the Shared AWS Configuration (~/.aws/config)
 	cfg, err := config.LoadDefaultConfig(context.TODO())
 	if err != nil {
@@ -31,6 +43,8 @@ func Open(bucket, uploadKeyPrefix, uploadURLPrefix string) (*YPSS3, error) {
 		bucket:          bucket,
 		uploadKeyPrefix: uploadKeyPrefix,
 		uploadURLPrefix: uploadURLPrefix,
+
+		privateKeyPrefix: privateKeyPrefix,
 	}
 
 	// Create an Amazon S3 service client
@@ -55,6 +69,57 @@ func (ys3 *YPSS3) Upload(key string, body io.Reader) (*S3Upload, error) {
 	}, nil
 }
 
+// UploadPrivate uploads the file under the private key prefix, so it isn't
+// served publicly.
+func (ys3 *YPSS3) UploadPrivate(key string, body io.Reader) error {
+	_, err := ys3.client.PutObject(context.TODO(), &s3.PutObjectInput{
+		Bucket: aws.String(ys3.bucket),
+		Key:    aws.String(ys3.privateKeyPrefix + key),
+		Body:   body,
+	})
+	return err
+}
+
+// PrivateURL returns a presigned URL for the private file, which stops working
+// after privateURLExpiry.
+func (ys3 *YPSS3) PrivateURL(key string) (string, error) {
+	presigned, err := s3.NewPresignClient(ys3.client).PresignGetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(ys3.bucket),
+		Key:    aws.String(ys3.privateKeyPrefix + key),
+	}, s3.WithPresignExpires(privateURLExpiry))
+	if err != nil {
+		return "", err
+	}
+	return presigned.URL, nil
+}
+
+// Publish copies the private file at privateKey to the public toKey,
+// returning the new file.
+func (ys3 *YPSS3) Publish(privateKey, toKey string) (*S3Upload, error) {
+	fromName := ys3.privateKeyPrefix + privateKey
+	name := fmt.Sprintf("%s%s", ys3.uploadKeyPrefix, toKey)
+	_, err := ys3.client.CopyObject(context.TODO(), &s3.CopyObjectInput{
+		Bucket:     aws.String(ys3.bucket),
+		CopySource: aws.String(url.PathEscape(ys3.bucket + "/" + fromName)),
+		Key:        aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &S3Upload{
+		Filename: name,
+		URL:      fmt.Sprintf("%s%s", ys3.uploadURLPrefix, toKey),
+	}, nil
+}
+
+func (ys3 *YPSS3) DeletePrivate(key string) error {
+	_, err := ys3.client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+		Bucket: aws.String(ys3.bucket),
+		Key:    aws.String(ys3.privateKeyPrefix + key),
+	})
+	return err
+}
+
 func (ys3 *YPSS3) Delete(key string) error {
 	name := fmt.Sprintf("%s%s", ys3.uploadKeyPrefix, key)
 	_, err := ys3.client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
@@ -84,6 +149,10 @@ func (ys3 *YPSS3) EntryFileKey(entryID, filename string) string {
 	return fmt.Sprintf("entries/%s/%s", entryID, filename)
 }
 
+func (ys3 *YPSS3) SubmissionFileKey(submissionID int, filename string) string {
+	return fmt.Sprintf("submissions/%d/%s", submissionID, filename)
+}
+
 func (ys3 *YPSS3) EntryFileURL(entryID, filename string) string {
 	return fmt.Sprintf("%sentries/%s/%s", ys3.uploadURLPrefix, entryID, filename)
 }
@@ -15,6 +15,9 @@ type SearchRequest struct {
 	FilterValue   string `form:"filterValue"`
 	Sort          string `form:"sort"`
 	Page          int    `form:"page"`
+
+	// for keyword filters, whether to include entries tagged with narrower terms
+	IncludeNarrower bool `form:"includeNarrower"`
 }
 
 type SearchFilterValue struct {
@@ -45,7 +48,12 @@ func searchEntries(c *gin.Context) {
 		return
 	}
 
-	response, err := TheDb.Search(req)
+	if req.SearchContext == "notes" && !isAdminRequest(c) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Only admins can search curator notes"})
+		return
+	}
+
+	response, err := TheDb.Search(req, isAdminRequest(c))
 	if err != nil {
 		fmt.Println("Could not search:", err.Error())
 		c.JSON(400, gin.H{"error": "Could not search"})
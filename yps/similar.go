@@ -0,0 +1,113 @@
+package yps
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSimilarEntries = 5
+	maxSimilarEntries     = 20
+)
+
+type SimilarEntry struct {
+	ID          string  `json:"id"`
+	Title       string  `json:"title"`
+	DocType     string  `json:"entry_type"`
+	DateDisplay string  `json:"date_display"`
+	Score       float64 `json:"score"`
+}
+
+// theSimilarEntries caches the public similar entries of each entry, up to
+// maxSimilarEntries. It's emptied whenever entries change, and each entry's
+// list is worked out again the next time it's asked for.
+var theSimilarEntries = make(map[string][]SimilarEntry)
+var theSimilarEntriesLock sync.Mutex
+
+// bumped on every clear, so lists worked out from old entries aren't kept
+var theSimilarEntriesGeneration int
+
+func clearSimilarEntries() {
+	theSimilarEntriesLock.Lock()
+	defer theSimilarEntriesLock.Unlock()
+	theSimilarEntries = make(map[string][]SimilarEntry)
+	theSimilarEntriesGeneration++
+}
+
+// handler
+
+type GetSimilarEntriesRequest struct {
+	Limit int `form:"limit"`
+}
+
+type GetSimilarEntriesResponse struct {
+	Similar []SimilarEntry `json:"similar"`
+}
+
+func getSimilarEntries(c *gin.Context) {
+	var req GetEntryRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		fmt.Println("Could not get entry URI binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Entry must be given"})
+		return
+	}
+
+	var params GetSimilarEntriesRequest
+	if err := c.ShouldBind(&params); err != nil {
+		fmt.Println("Could not get similar entries binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not read params"})
+		return
+	}
+	if params.Limit < 1 {
+		params.Limit = defaultSimilarEntries
+	} else if params.Limit > maxSimilarEntries {
+		params.Limit = maxSimilarEntries
+	}
+
+	// admins see entries the public can't, so their results aren't cached
+	isAdmin := isAdminRequest(c)
+	theSimilarEntriesLock.Lock()
+	similar, cached := theSimilarEntries[req.ID]
+	generation := theSimilarEntriesGeneration
+	theSimilarEntriesLock.Unlock()
+	if cached && !isAdmin {
+		c.JSON(http.StatusOK, GetSimilarEntriesResponse{
+			Similar: similar[:min(params.Limit, len(similar))],
+		})
+		return
+	}
+
+	_, err := TheDb.GetSingleEntry(req.ID, isAdmin)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondMissingEntry(c, req.ID)
+		return
+	} else if err != nil {
+		fmt.Println("Could not get entry:", req.ID, ":", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get entry"})
+		return
+	}
+
+	similar, err = TheDb.GetSimilarEntries(req.ID, maxSimilarEntries, isAdmin)
+	if err != nil {
+		fmt.Println("Could not get similar entries:", req.ID, ":", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get similar entries"})
+		return
+	}
+
+	if !isAdmin {
+		theSimilarEntriesLock.Lock()
+		if generation == theSimilarEntriesGeneration {
+			theSimilarEntries[req.ID] = similar
+		}
+		theSimilarEntriesLock.Unlock()
+	}
+
+	c.JSON(http.StatusOK, GetSimilarEntriesResponse{
+		Similar: similar[:min(params.Limit, len(similar))],
+	})
+}
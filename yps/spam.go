@@ -0,0 +1,39 @@
+package yps
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// public forms include a field named this that's hidden from people, so only
+// bots fill it in
+const honeypotField = "website"
+
+// rejectSpam turns away posts of the given kind, like 'submission', that have
+// the honeypot field filled in or that go over the IP's hourly limit. If it
+// does, an error response is written and rejected is true.
+func rejectSpam(c *gin.Context, kind, honeypotValue string, countRecent func(ip string, since time.Time) (int, error), perHourLimit int) (rejected bool) {
+	if honeypotValue != "" {
+		Log(LogLevelWarning, kind+"-spam", fmt.Sprintf("Ignored %s with the honeypot field filled in", kind), map[string]string{
+			"ip": c.ClientIP(),
+		})
+		c.JSON(400, gin.H{"error": "Could not accept " + kind})
+		return true
+	}
+
+	count, err := countRecent(c.ClientIP(), time.Now().Add(-time.Hour))
+	if err != nil {
+		fmt.Printf("Could not count recent %ss: %s\n", kind, err.Error())
+		c.JSON(400, gin.H{"error": "Could not accept " + kind})
+		return true
+	}
+	if count >= perHourLimit {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("Too many %ss, please try again later", kind)})
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,538 @@
+package yps
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/google/uuid"
+)
+
+// spam protection for public submissions
+const (
+	submissionsPerHourLimit = 5
+	maxSubmissionFiles      = 5
+	maxSubmissionFileSize   = 20 << 20
+	// leaves room for the entry fields and multipart headers
+	maxSubmissionBodySize = maxSubmissionFiles*maxSubmissionFileSize + 1<<20
+)
+
+type SubmissionStatus string
+
+const (
+	SubmissionStatusPending SubmissionStatus = "pending"
+	// claimed by an admin, while its entry is being created
+	SubmissionStatusApproving SubmissionStatus = "approving"
+	SubmissionStatusApproved  SubmissionStatus = "approved"
+	SubmissionStatusRejected  SubmissionStatus = "rejected"
+)
+
+// Submission is an entry suggested by the public, waiting for an admin to
+// approve or reject it.
+type Submission struct {
+	ID             int              `json:"id"`
+	Status         SubmissionStatus `json:"status"`
+	SubmittedAt    time.Time        `json:"submitted_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+	SubmitterName  string           `json:"submitter_name"`
+	SubmitterEmail string           `json:"submitter_email"`
+	SubmitterIP    string           `json:"submitter_ip"`
+	Fields         EditEntryParams  `json:"fields"`
+	Files          []EntryFile      `json:"files"`
+	// the entry it became, once approved
+	EntryID string `json:"entry_id"`
+	// shown to the submitter, mostly to say why it was rejected
+	ReviewNote string `json:"review_note"`
+}
+
+// publicSubmissionFields drops the fields that only admins get to set.
+func publicSubmissionFields(fields EditEntryParams) EditEntryParams {
+	fields.PreviousIDs = nil
+	fields.Visibility = nil
+	fields.EmbargoUntil = nil
+	fields.Notes = nil
+	return fields
+}
+
+// uploadSubmissionFile stores the file privately, so nothing sent in is public
+// until it's been approved.
+func uploadSubmissionFile(submissionID int, fileHeader *multipart.FileHeader) (EntryFile, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return EntryFile{}, err
+	}
+	defer file.Close()
+
+	err = TheS3.UploadPrivate(TheS3.SubmissionFileKey(submissionID, fileHeader.Filename), file)
+	if err != nil {
+		return EntryFile{}, err
+	}
+	return EntryFile{
+		Filename: fileHeader.Filename,
+	}, nil
+}
+
+// withSubmissionFileURLs fills in short-lived links to the submission's files
+// for admins to look at.
+func withSubmissionFileURLs(sub Submission) Submission {
+	files := make([]EntryFile, len(sub.Files))
+	for i, file := range sub.Files {
+		url, err := TheS3.PrivateURL(TheS3.SubmissionFileKey(sub.ID, file.Filename))
+		if err != nil {
+			fmt.Println("Could not get submission file URL:", sub.ID, file.Filename, ":", err.Error())
+		}
+		files[i] = EntryFile{
+			Filename: file.Filename,
+			URL:      url,
+		}
+	}
+	sub.Files = files
+	return sub
+}
+
+// deleteSubmissionFiles removes the uploaded copies of the submission's files.
+func deleteSubmissionFiles(sub Submission) {
+	for _, file := range sub.Files {
+		err := TheS3.DeletePrivate(TheS3.SubmissionFileKey(sub.ID, file.Filename))
+		if err != nil {
+			fmt.Println("Could not delete submission file:", sub.ID, file.Filename, ":", err.Error())
+		}
+	}
+}
+
+// handler
+
+func createSubmission(c *gin.Context) {
+	// files are optional, and forms without any may not be multipart at all
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSubmissionBodySize)
+	form, err := c.MultipartForm()
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Submission is larger than %dMB", maxSubmissionBodySize>>20)})
+		return
+	} else if err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		fmt.Println("Could not read submission form:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not read submission"})
+		return
+	}
+
+	if rejectSpam(c, "submission", c.PostForm(honeypotField), TheDb.CountRecentSubmissions, submissionsPerHourLimit) {
+		return
+	}
+
+	var fields EditEntryParams
+	if err := json.Unmarshal([]byte(c.PostForm("entry")), &fields); err != nil {
+		c.JSON(400, gin.H{"error": "Entry fields must be given as JSON in the 'entry' form field"})
+		return
+	}
+	fields = publicSubmissionFields(fields)
+	if fields.Title == nil || strings.TrimSpace(*fields.Title) == "" {
+		c.JSON(400, gin.H{"error": "Title must be given"})
+		return
+	}
+	if _, err := fields.entryColumns(Entry{}); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub := Submission{
+		SubmitterName:  strings.TrimSpace(c.PostForm("name")),
+		SubmitterEmail: strings.TrimSpace(c.PostForm("email")),
+		SubmitterIP:    c.ClientIP(),
+		Fields:         fields,
+		Files:          []EntryFile{},
+	}
+	if sub.SubmitterEmail != "" {
+		if _, err := mail.ParseAddress(sub.SubmitterEmail); err != nil {
+			c.JSON(400, gin.H{"error": "Email address is not valid"})
+			return
+		}
+	}
+
+	if form != nil {
+		fileHeaders := form.File["files"]
+		if len(fileHeaders) > maxSubmissionFiles {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("At most %d files can be uploaded", maxSubmissionFiles)})
+			return
+		}
+		seenFilenames := make(map[string]bool)
+		for _, fileHeader := range fileHeaders {
+			if fileHeader.Size > maxSubmissionFileSize {
+				c.JSON(400, gin.H{"error": fmt.Sprintf("File %s is larger than %dMB", fileHeader.Filename, maxSubmissionFileSize>>20)})
+				return
+			}
+			if seenFilenames[fileHeader.Filename] {
+				c.JSON(400, gin.H{"error": fmt.Sprintf("File %s is uploaded more than once", fileHeader.Filename)})
+				return
+			}
+			seenFilenames[fileHeader.Filename] = true
+		}
+	}
+
+	token, err := uuid.NewRandom()
+	if err != nil {
+		fmt.Println("Could not generate submission token:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not accept submission"})
+		return
+	}
+
+	sub.ID, err = TheDb.CreateSubmission(sub, token.String())
+	if err != nil {
+		fmt.Println("Could not create submission:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not accept submission"})
+		return
+	}
+
+	// files are stored under the submission's ID, so they go up once it exists
+	if form != nil {
+		for _, fileHeader := range form.File["files"] {
+			entryFile, err := uploadSubmissionFile(sub.ID, fileHeader)
+			if err != nil {
+				fmt.Println("Could not upload submission file:", err.Error())
+				deleteSubmissionFiles(sub)
+				err = TheDb.DeleteSubmission(sub.ID)
+				if err != nil {
+					fmt.Println("Could not delete submission:", err.Error())
+				}
+				c.JSON(400, gin.H{"error": fmt.Sprintf("Could not upload file %s", fileHeader.Filename)})
+				return
+			}
+			sub.Files = append(sub.Files, entryFile)
+		}
+
+		err = TheDb.SetSubmissionFiles(sub.ID, sub.Files)
+		if err != nil {
+			fmt.Println("Could not save submission files:", err.Error())
+			c.JSON(400, gin.H{"error": "Could not save submission files"})
+			return
+		}
+	}
+
+	Log(LogLevelInfo, "submission-create", fmt.Sprintf("Received submission %d", sub.ID), map[string]any{
+		"submission": sub.ID,
+		"title":      *sub.Fields.Title,
+		"files":      len(sub.Files),
+		"ip":         sub.SubmitterIP,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"ok":     true,
+		"id":     sub.ID,
+		"token":  token.String(),
+		"status": SubmissionStatusPending,
+	})
+}
+
+type GetSubmissionStatusRequest struct {
+	Token string `uri:"slug" binding:"required"`
+}
+
+type SubmissionStatusResponse struct {
+	Status      SubmissionStatus `json:"status"`
+	SubmittedAt time.Time        `json:"submitted_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	EntryID     string           `json:"entry_id,omitempty"`
+	ReviewNote  string           `json:"review_note,omitempty"`
+}
+
+func getSubmissionStatus(c *gin.Context) {
+	var req GetSubmissionStatusRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		fmt.Println("Could not get submission status URI binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Token must be given"})
+		return
+	}
+
+	sub, err := TheDb.GetSubmissionByToken(req.Token)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return
+	} else if err != nil {
+		fmt.Println("Could not get submission:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get submission"})
+		return
+	}
+
+	c.JSON(http.StatusOK, SubmissionStatusResponse{
+		Status:      sub.Status,
+		SubmittedAt: sub.SubmittedAt,
+		UpdatedAt:   sub.UpdatedAt,
+		EntryID:     sub.EntryID,
+		ReviewNote:  sub.ReviewNote,
+	})
+}
+
+type GetSubmissionsRequest struct {
+	Status SubmissionStatus `form:"status"`
+}
+
+type GetSubmissionsResponse struct {
+	Submissions []Submission `json:"submissions"`
+}
+
+func getSubmissions(c *gin.Context) {
+	var req GetSubmissionsRequest
+	if err := c.ShouldBind(&req); err != nil {
+		fmt.Println("Could not get submissions binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not read params"})
+		return
+	}
+	switch req.Status {
+	case "", SubmissionStatusPending, SubmissionStatusApproving, SubmissionStatusApproved, SubmissionStatusRejected:
+	default:
+		c.JSON(400, gin.H{"error": fmt.Sprintf("status must be one of: %s, %s, %s, %s", SubmissionStatusPending, SubmissionStatusApproving, SubmissionStatusApproved, SubmissionStatusRejected)})
+		return
+	}
+
+	submissions, err := TheDb.GetSubmissions(req.Status)
+	if err != nil {
+		fmt.Println("Could not get submissions:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get submissions"})
+		return
+	}
+
+	for i := range submissions {
+		submissions[i] = withSubmissionFileURLs(submissions[i])
+	}
+
+	c.JSON(http.StatusOK, GetSubmissionsResponse{
+		Submissions: submissions,
+	})
+}
+
+type SubmissionRequest struct {
+	ID int `uri:"id" binding:"required"`
+}
+
+// getSubmissionFromURI looks up the submission given in the URI. If it
+// can't, an error response is written and found is false.
+func getSubmissionFromURI(c *gin.Context) (sub Submission, found bool) {
+	var req SubmissionRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		fmt.Println("Could not get submission URI binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Submission must be given"})
+		return sub, false
+	}
+
+	sub, err := TheDb.GetSubmission(req.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return sub, false
+	} else if err != nil {
+		fmt.Println("Could not get submission:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get submission"})
+		return sub, false
+	}
+
+	return sub, true
+}
+
+func getSubmission(c *gin.Context) {
+	sub, found := getSubmissionFromURI(c)
+	if !found {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"submission": withSubmissionFileURLs(sub)})
+}
+
+// editSubmission replaces the entry fields of a pending submission.
+func editSubmission(c *gin.Context) {
+	sub, found := getSubmissionFromURI(c)
+	if !found {
+		return
+	}
+
+	var params EditEntryParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := params.entryColumns(Entry{}); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := TheDb.UpdateSubmissionFields(sub.ID, params)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(400, gin.H{"error": "Submission has already been reviewed"})
+		return
+	} else if err != nil {
+		fmt.Println("Could not update submission:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not update submission"})
+		return
+	}
+
+	Log(LogLevelInfo, "submission-update", fmt.Sprintf("Updated submission %d", sub.ID), map[string]any{
+		"submission": sub.ID,
+		"fields":     params,
+	})
+
+	sub.Fields = params
+	c.JSON(http.StatusOK, gin.H{"ok": true, "submission": withSubmissionFileURLs(sub)})
+}
+
+type ApproveSubmissionParams struct {
+	// item ID of the new entry
+	ID   string `json:"id" binding:"required"`
+	Note string `json:"note"`
+}
+
+// approveSubmission creates a new entry from the submission, along with its files.
+func approveSubmission(c *gin.Context) {
+	sub, found := getSubmissionFromURI(c)
+	if !found {
+		return
+	}
+	if sub.Status != SubmissionStatusPending {
+		c.JSON(400, gin.H{"error": "Submission has already been reviewed"})
+		return
+	}
+
+	var params ApproveSubmissionParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lock := acquireImportLockOrConflict(c, fmt.Sprintf("approval of submission %d", sub.ID))
+	if lock == nil {
+		return
+	}
+	defer lock.Release()
+
+	allEntries, err := TheDb.GetAllEntries()
+	if err != nil {
+		fmt.Println("Could not get entries:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get entries"})
+		return
+	}
+	id, err := checkNewEntryID(params.ID, allEntries)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	// claiming it means no one else can approve, reject or edit it from here
+	err = TheDb.ReviewSubmission(sub.ID, SubmissionStatusPending, SubmissionStatusApproving, "", sub.ReviewNote)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(400, gin.H{"error": "Submission has already been reviewed"})
+		return
+	} else if err != nil {
+		fmt.Println("Could not claim submission:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not approve submission"})
+		return
+	}
+	// its fields may have been edited since it was first looked up
+	claimed, err := TheDb.GetSubmission(sub.ID)
+	if err == nil {
+		newEntries, saved := saveEntryFromParams(c, fmt.Sprintf("submission %d", sub.ID), Entry{ItemID: id}, claimed.Fields, allEntries)
+		if saved {
+			finishSubmissionApproval(c, claimed, id, strings.TrimSpace(params.Note), newEntries)
+			return
+		}
+	} else {
+		fmt.Println("Could not get submission:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get submission"})
+	}
+
+	// nothing was created, so it's back up for review
+	err = TheDb.ReviewSubmission(sub.ID, SubmissionStatusApproving, SubmissionStatusPending, "", sub.ReviewNote)
+	if err != nil {
+		fmt.Println("Could not release submission:", err.Error())
+	}
+}
+
+// finishSubmissionApproval moves the files of a claimed submission over to
+// its new entry, and marks it approved.
+func finishSubmissionApproval(c *gin.Context, sub Submission, id, note string, newEntries *EntriesXLSX) {
+	// the entry exists now, so file problems are reported rather than failing the approval
+	remainingFiles := []EntryFile{}
+	for _, file := range sub.Files {
+		key := TheS3.SubmissionFileKey(sub.ID, file.Filename)
+		uploaded, err := TheS3.Publish(key, TheS3.EntryFileKey(id, file.Filename))
+		if err == nil {
+			err = TheDb.AddEntryFile(id, file.Filename, uploaded.URL)
+		}
+		if err != nil {
+			fmt.Println("Could not copy submission file:", sub.ID, file.Filename, ":", err.Error())
+			newEntries.Nits = append(newEntries.Nits, fmt.Sprintf("Could not attach file %s, it'll need to be uploaded again.", file.Filename))
+			remainingFiles = append(remainingFiles, file)
+			continue
+		}
+
+		err = TheS3.DeletePrivate(key)
+		if err != nil {
+			fmt.Println("Could not delete submission file:", sub.ID, file.Filename, ":", err.Error())
+		}
+	}
+	// files that couldn't be attached stay with the submission, so they can still be fetched
+	err := TheDb.SetSubmissionFiles(sub.ID, remainingFiles)
+	if err != nil {
+		fmt.Println("Could not update submission files:", err.Error())
+	}
+
+	err = TheDb.ReviewSubmission(sub.ID, SubmissionStatusApproving, SubmissionStatusApproved, id, note)
+	if err != nil {
+		fmt.Println("Could not mark submission as approved:", err.Error())
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Entry %s was created, but submission %d could not be marked as approved", id, sub.ID)})
+		return
+	}
+
+	Log(LogLevelInfo, "submission-approve", fmt.Sprintf("Approved submission %d as entry %s", sub.ID, id), map[string]any{
+		"submission": sub.ID,
+		"entry":      id,
+	})
+
+	respondSavedEntry(c, id, newEntries)
+}
+
+type RejectSubmissionParams struct {
+	// shown to the submitter
+	Reason string `json:"reason"`
+}
+
+// rejectSubmission closes the submission without making an entry, and removes its files.
+func rejectSubmission(c *gin.Context) {
+	sub, found := getSubmissionFromURI(c)
+	if !found {
+		return
+	}
+
+	var params RejectSubmissionParams
+	if err := c.ShouldBindJSON(&params); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := TheDb.ReviewSubmission(sub.ID, SubmissionStatusPending, SubmissionStatusRejected, "", strings.TrimSpace(params.Reason))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(400, gin.H{"error": "Submission has already been reviewed"})
+		return
+	} else if err != nil {
+		fmt.Println("Could not reject submission:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not reject submission"})
+		return
+	}
+
+	deleteSubmissionFiles(sub)
+	err = TheDb.SetSubmissionFiles(sub.ID, []EntryFile{})
+	if err != nil {
+		fmt.Println("Could not clear submission files:", err.Error())
+	}
+
+	Log(LogLevelInfo, "submission-reject", fmt.Sprintf("Rejected submission %d", sub.ID), map[string]any{
+		"submission": sub.ID,
+		"reason":     params.Reason,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
@@ -0,0 +1,222 @@
+package yps
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	ypsc "github.com/YPS-Database/yps-db-backend/yps/columns"
+	ypsl "github.com/YPS-Database/yps-db-backend/yps/languages"
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// how many rows of the template get data validation applied to them
+const templateValidatedRows = 5000
+
+const (
+	templateDatabaseSheet     = "Database"
+	templateInstructionsSheet = "Instructions"
+	templateListsSheet        = "Lists"
+)
+
+// the default vocabulary rules classify all of these
+var templateYouthLedValues = []string{"Yes", "No", "Co-authored", "N/A"}
+
+var templateColumnDescriptions = map[ypsc.ColumnType]string{
+	ypsc.ItemID:                   "Unique ID for this entry. Must not be repeated.",
+	ypsc.Authors:                  "Authors of the document, separated by semicolons. End with 'et al.' if the list is incomplete.",
+	ypsc.Year:                     "Year the document was published, or N/A.",
+	ypsc.Title:                    "Title of the document. Rows without a title are skipped.",
+	ypsc.OrgPublisher:             "Organisations or publishers, separated by semicolons. Use the names or aliases from the organisations list.",
+	ypsc.DocNumber:                "The publisher's own document number, or N/A.",
+	ypsc.DayMonth:                 "Day and month of publication, e.g. 'March' or '3 March - 5 April', or N/A.",
+	ypsc.URL:                      "Link to the document.",
+	ypsc.Languages:                "Languages this document is available in, separated by commas. Use the names from the dropdown.",
+	ypsc.AlternateLanguageEntries: "Item IDs of this same document in other languages, separated by commas.",
+	ypsc.RelatedEntries:           "Item IDs of related documents, separated by commas. Give the type of relationship like 'supersedes:123' where it's known.",
+	ypsc.YouthInvolvement:         "Whether the document is youth-led. Start with 'Yes' or 'No', or include 'Co-authored'. Further detail can follow.",
+	ypsc.Abstract:                 "Abstract or executive summary.",
+	ypsc.OrgType:                  "Type of organisation that published the document.",
+	ypsc.DocType:                  "Type of document.",
+	ypsc.Keywords:                 "Keywords, separated by semicolons.",
+	ypsc.PreviousIDs:              "Optional. Item IDs this document used to have, separated by commas, so old links to them keep working.",
+	ypsc.Visibility:               "Optional. Published, Draft, Hidden, or 'Embargoed until YYYY-MM-DD'. Blank keeps an existing entry's visibility, and publishes new ones.",
+	ypsc.Notes:                    "Optional. Private notes for curators, never shown to the public. If this column is left out, existing notes are kept.",
+}
+
+func templateListRange(column string, length int) string {
+	return fmt.Sprintf("%s!$%s$2:$%s$%d", templateListsSheet, column, column, length+1)
+}
+
+// WriteTemplateFile returns a blank import spreadsheet with the columns that ReadEntriesFile expects.
+func WriteTemplateFile(docTypes, orgTypes []string) (*bytes.Buffer, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	err := f.SetSheetName("Sheet1", templateDatabaseSheet)
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.NewSheet(templateInstructionsSheet)
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.NewSheet(templateListsSheet)
+	if err != nil {
+		return nil, err
+	}
+
+	// vocabulary lists that the dropdowns point to
+	languages := ypsl.Names()
+	slices.SortFunc(languages, func(a, b string) int {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	})
+	lists := [][]string{
+		languages,
+		{"0", "1"},
+		templateYouthLedValues,
+		docTypes,
+		orgTypes,
+	}
+	listHeaders := []string{"Languages", "Region flags", "Youth-led", "Document types", "Org types"}
+	var listRanges []string
+	for i, list := range lists {
+		listColumn, err := excelize.ColumnNumberToName(i + 1)
+		if err != nil {
+			return nil, err
+		}
+		err = f.SetCellValue(templateListsSheet, listColumn+"1", listHeaders[i])
+		if err != nil {
+			return nil, err
+		}
+		for j, value := range list {
+			err = f.SetCellValue(templateListsSheet, fmt.Sprintf("%s%d", listColumn, j+2), value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		listRanges = append(listRanges, templateListRange(listColumn, len(list)))
+	}
+	err = f.SetSheetVisible(templateListsSheet, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// database headers and dropdowns
+	for i, columnType := range ypsc.SheetColumns {
+		column, err := excelize.ColumnNumberToName(i + 1)
+		if err != nil {
+			return nil, err
+		}
+		err = f.SetCellValue(templateDatabaseSheet, column+"1", columnType.String())
+		if err != nil {
+			return nil, err
+		}
+
+		var listRange string
+		strict := false
+		if columnType == ypsc.Languages {
+			// several languages can be given, so this is only a suggestion
+			listRange = listRanges[0]
+		} else if slices.Contains(ypsc.RegionColumns, columnType) {
+			listRange = listRanges[1]
+			strict = true
+		} else if columnType == ypsc.YouthInvolvement {
+			listRange = listRanges[2]
+		} else if columnType == ypsc.DocType && len(docTypes) > 0 {
+			listRange = listRanges[3]
+		} else if columnType == ypsc.OrgType && len(orgTypes) > 0 {
+			listRange = listRanges[4]
+		}
+		if listRange == "" {
+			continue
+		}
+
+		dv := excelize.NewDataValidation(true)
+		dv.SetSqref(fmt.Sprintf("%s2:%s%d", column, column, templateValidatedRows))
+		dv.SetSqrefDropList(listRange)
+		if strict {
+			dv.SetError(excelize.DataValidationErrorStyleStop, columnType.String(), "Must be 0 or 1.")
+		} else {
+			dv.SetError(excelize.DataValidationErrorStyleWarning, columnType.String(), "This isn't one of the usual values, are you sure?")
+		}
+		err = f.AddDataValidation(templateDatabaseSheet, dv)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = f.SetPanes(templateDatabaseSheet, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// instructions
+	instructions := [][]any{
+		{"YPS Database import template"},
+		{"Fill in one row per document on the '" + templateDatabaseSheet + "' sheet. Do not rename, remove or reorder the column headers."},
+		{"Region columns take 1 if the document covers that region, otherwise 0. If no region is marked, the entry is marked as N/A."},
+		{},
+		{"Column", "Description"},
+	}
+	for _, columnType := range ypsc.SheetColumns {
+		description, exists := templateColumnDescriptions[columnType]
+		if !exists && slices.Contains(ypsc.RegionColumns, columnType) {
+			description = "1 if the document covers this region, otherwise 0."
+		}
+		instructions = append(instructions, []any{columnType.String(), description})
+	}
+	for i, row := range instructions {
+		err = f.SetSheetRow(templateInstructionsSheet, fmt.Sprintf("A%d", i+1), &row)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = f.SetColWidth(templateInstructionsSheet, "A", "A", 30)
+	if err != nil {
+		return nil, err
+	}
+	err = f.SetColWidth(templateInstructionsSheet, "B", "B", 100)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.WriteToBuffer()
+}
+
+// handler
+
+func getYpsDbTemplate(c *gin.Context) {
+	docTypes, err := TheDb.GetDistinctEntryValues("entry_type")
+	if err != nil {
+		fmt.Println("Could not get document types:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get document types"})
+		return
+	}
+
+	orgTypes, err := TheDb.GetDistinctEntryValues("org_type")
+	if err != nil {
+		fmt.Println("Could not get org types:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get org types"})
+		return
+	}
+
+	buf, err := WriteTemplateFile(docTypes, orgTypes)
+	if err != nil {
+		fmt.Println("Could not write template file:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not create template file"})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="yps-database-template.xlsx"`)
+	c.Data(http.StatusOK, xlsxContentType, buf.Bytes())
+}
@@ -0,0 +1,224 @@
+package yps
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	ypsl "github.com/YPS-Database/yps-db-backend/yps/languages"
+	"github.com/gin-gonic/gin"
+)
+
+// the UN's official languages
+var DefaultTranslationTargets = []string{"ar", "zh", "en", "fr", "ru", "es"}
+
+var translationTargets []string
+
+func init() {
+	SetTranslationTargets(DefaultTranslationTargets)
+}
+
+// parseLanguageCodes takes language codes or names, and returns their codes.
+func parseLanguageCodes(languages []string) (codes []string, err error) {
+	for _, language := range languages {
+		language = strings.TrimSpace(language)
+		if language == "" {
+			continue
+		}
+		code := strings.ToLower(language)
+		if ypsl.GetName(code) == "Unknown" {
+			code, err = ypsl.GetCode(language)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !slices.Contains(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	return codes, nil
+}
+
+// SetTranslationTargets sets the languages the translation coverage report
+// expects every entry to be available in. They can be codes or names.
+func SetTranslationTargets(languages []string) error {
+	codes, err := parseLanguageCodes(languages)
+	if err != nil {
+		return err
+	}
+	translationTargets = codes
+	return nil
+}
+
+type TranslationEntry struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Files int    `json:"files"`
+}
+
+// TranslationGroup is a set of entries that are translations of each other.
+type TranslationGroup struct {
+	// the lowest item ID in the group
+	ID        string                        `json:"id"`
+	Languages map[string][]TranslationEntry `json:"languages"`
+	Missing   []string                      `json:"missing"`
+}
+
+type TranslationLanguageTotal struct {
+	Language string `json:"language"`
+	Name     string `json:"name"`
+	Entries  int    `json:"entries"`
+	Files    int    `json:"files"`
+	// groups that have an entry in this language
+	Groups int `json:"groups"`
+	// groups without an entry in this language, only counted for targets
+	MissingFrom int `json:"missing_from"`
+}
+
+// translationGroups joins entries that list each other as alternates, even
+// indirectly, into groups. Alternates that don't exist are ignored.
+func translationGroups(entries map[string]Entry) (groups [][]string) {
+	parent := make(map[string]string)
+	var find func(id string) string
+	find = func(id string) string {
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+	for id := range entries {
+		parent[id] = id
+	}
+	for id, entry := range entries {
+		for _, altID := range entry.AltLanguageIDs {
+			if _, exists := entries[altID]; exists {
+				parent[find(altID)] = find(id)
+			}
+		}
+	}
+
+	members := make(map[string][]string)
+	for id := range entries {
+		root := find(id)
+		members[root] = append(members[root], id)
+	}
+	for _, ids := range members {
+		slices.SortFunc(ids, compareItemIDs)
+		groups = append(groups, ids)
+	}
+	slices.SortFunc(groups, func(a, b []string) int {
+		return compareItemIDs(a[0], b[0])
+	})
+	return groups
+}
+
+// handler
+
+type GetTranslationCoverageRequest struct {
+	// comma-separated codes or names, instead of the configured targets
+	Targets     string `form:"targets"`
+	MissingOnly bool   `form:"missing_only"`
+}
+
+type GetTranslationCoverageResponse struct {
+	Targets []string                   `json:"targets"`
+	Groups  []TranslationGroup         `json:"groups"`
+	Totals  []TranslationLanguageTotal `json:"totals"`
+}
+
+func getTranslationCoverage(c *gin.Context) {
+	var req GetTranslationCoverageRequest
+	if err := c.ShouldBind(&req); err != nil {
+		fmt.Println("Could not get translation coverage binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not read params"})
+		return
+	}
+
+	targets := translationTargets
+	if req.Targets != "" {
+		var err error
+		targets, err = parseLanguageCodes(strings.Split(req.Targets, ","))
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	entries, err := TheDb.GetAllEntries()
+	if err != nil {
+		fmt.Println("Could not get entries:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get entries"})
+		return
+	}
+	fileCounts, err := TheDb.GetEntryFileCounts()
+	if err != nil {
+		fmt.Println("Could not get entry file counts:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get entry files"})
+		return
+	}
+
+	response := GetTranslationCoverageResponse{
+		Targets: targets,
+		Groups:  []TranslationGroup{},
+		Totals:  []TranslationLanguageTotal{},
+	}
+	totals := make(map[string]*TranslationLanguageTotal)
+	getTotal := func(language string) *TranslationLanguageTotal {
+		if totals[language] == nil {
+			totals[language] = &TranslationLanguageTotal{
+				Language: language,
+				Name:     ypsl.GetName(language),
+			}
+		}
+		return totals[language]
+	}
+	for _, language := range targets {
+		getTotal(language)
+	}
+
+	for _, ids := range translationGroups(entries) {
+		group := TranslationGroup{
+			ID:        ids[0],
+			Languages: make(map[string][]TranslationEntry),
+			Missing:   []string{},
+		}
+		for _, id := range ids {
+			entry := entries[id]
+			group.Languages[entry.Language] = append(group.Languages[entry.Language], TranslationEntry{
+				ID:    id,
+				Title: entry.Title,
+				Files: fileCounts[id],
+			})
+
+			total := getTotal(entry.Language)
+			total.Entries++
+			total.Files += fileCounts[id]
+		}
+		for language := range group.Languages {
+			getTotal(language).Groups++
+		}
+		for _, language := range targets {
+			if _, exists := group.Languages[language]; !exists {
+				group.Missing = append(group.Missing, language)
+				getTotal(language).MissingFrom++
+			}
+		}
+
+		if !req.MissingOnly || len(group.Missing) > 0 {
+			response.Groups = append(response.Groups, group)
+		}
+	}
+
+	for _, total := range totals {
+		response.Totals = append(response.Totals, *total)
+	}
+	slices.SortFunc(response.Totals, func(a, b TranslationLanguageTotal) int {
+		if a.Entries != b.Entries {
+			return b.Entries - a.Entries
+		}
+		return strings.Compare(a.Language, b.Language)
+	})
+
+	c.JSON(http.StatusOK, response)
+}
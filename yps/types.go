@@ -30,24 +30,52 @@ type LogLine struct {
 // entries
 
 type Entry struct {
-	ItemID          string    `json:"id"`
-	Title           string    `json:"title"`
-	Authors         string    `json:"authors"`
-	URL             string    `json:"url"`
-	OrgPublishers   []string  `json:"orgs"`
-	OrgDocID        string    `json:"org_doc_id"`
-	OrgType         string    `json:"org_type"`
-	DocType         string    `json:"entry_type"`
-	Abstract        string    `json:"abstract"`
-	YouthLed        string    `json:"youth_led"`
-	YouthLedDetails string    `json:"youth_led_details"`
-	Keywords        []string  `json:"keywords"`
-	Regions         []string  `json:"regions"`
-	StartDate       time.Time `json:"start_date"`
-	EndDate         time.Time `json:"end_date"`
-	Language        string    `json:"language"`
-	AltLanguageIDs  []string  `json:"alt_language_ids"`
-	RelatedIDs      []string  `json:"related_ids"`
+	ItemID          string          `json:"id"`
+	Title           string          `json:"title"`
+	Authors         []string        `json:"authors"`
+	AuthorsEtAl     bool            `json:"authors_et_al"`
+	URL             string          `json:"url"`
+	OrgPublishers   []string        `json:"orgs"`
+	OrgDocID        string          `json:"org_doc_id"`
+	OrgType         string          `json:"org_type"`
+	DocType         string          `json:"entry_type"`
+	Abstract        string          `json:"abstract"`
+	YouthLed        string          `json:"youth_led"`
+	YouthLedDetails string          `json:"youth_led_details"`
+	Keywords        []string        `json:"keywords"`
+	Regions         []string        `json:"regions"`
+	StartDate       time.Time       `json:"start_date"`
+	EndDate         time.Time       `json:"end_date"`
+	DatePrecision   DatePrecision   `json:"date_precision"`
+	DateDisplay     string          `json:"date_display"`
+	Language        string          `json:"language"`
+	AltLanguageIDs  []string        `json:"alt_language_ids"`
+	RelatedIDs      []string        `json:"related_ids"`
+	Relations       []EntryRelation `json:"relations"`
+	// old IDs that now lead to this entry
+	PreviousIDs []string `json:"previous_ids"`
+
+	Visibility   EntryVisibility `json:"visibility"`
+	EmbargoUntil *time.Time      `json:"embargo_until"`
+
+	// private curator notes, only given to admins
+	Notes string `json:"notes,omitempty"`
+
+	// set if the entry was created or changed outside of a spreadsheet import
+	ManuallyEditedAt *time.Time `json:"manually_edited_at"`
+}
+
+// EntryRelation is a typed link from an entry to another one, see RelationType.
+type EntryRelation struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type RelationType struct {
+	Name         string `json:"name"`
+	Label        string `json:"label"`
+	InverseLabel string `json:"inverse_label"`
+	Symmetric    bool   `json:"symmetric"`
 }
 
 type EntryFile struct {
@@ -69,6 +97,21 @@ type LookedUpEntry struct {
 	Alternates map[string]LookedUpAltLanguageEntry `json:"alternates"`
 
 	Related map[string]string `json:"related"`
+
+	// entries that list this one as related, but that this one doesn't list back
+	ReferencedBy map[string]string `json:"referenced_by"`
+
+	Relations []LookedUpRelationGroup `json:"relations"`
+}
+
+// LookedUpRelationGroup is the entries linked to an entry by one relation type,
+// in one direction. Inverse groups are entries that link to this one, and use
+// the type's inverse label. Symmetric types only have a single group.
+type LookedUpRelationGroup struct {
+	Type    string            `json:"type"`
+	Label   string            `json:"label"`
+	Inverse bool              `json:"inverse"`
+	Entries map[string]string `json:"entries"`
 }
 
 func (luEntry *LookedUpEntry) AsEntryResponse() (response GetEntryResponse) {
@@ -76,25 +119,31 @@ func (luEntry *LookedUpEntry) AsEntryResponse() (response GetEntryResponse) {
 	response.Files = luEntry.Files
 	response.Alternates = luEntry.Alternates
 	response.Related = luEntry.Related
+	response.ReferencedBy = luEntry.ReferencedBy
+	response.Relations = luEntry.Relations
 	return response
 }
 
 type SearchEntry struct {
-	ID                 string    `json:"id"`
-	Title              string    `json:"title"`
-	Authors            string    `json:"authors"`
-	StartDate          time.Time `json:"start_date"`
-	EndDate            time.Time `json:"end_date"`
-	DocumentType       string    `json:"document_type"`
-	AvailableLanguages []string  `json:"available_languages"`
-	Regions            []string  `json:"regions"`
-	Language           string    `json:"language"`
+	ID                 string        `json:"id"`
+	Title              string        `json:"title"`
+	Authors            []string      `json:"authors"`
+	AuthorsEtAl        bool          `json:"authors_et_al"`
+	StartDate          time.Time     `json:"start_date"`
+	EndDate            time.Time     `json:"end_date"`
+	DatePrecision      DatePrecision `json:"date_precision"`
+	DateDisplay        string        `json:"date_display"`
+	DocumentType       string        `json:"document_type"`
+	AvailableLanguages []string      `json:"available_languages"`
+	Regions            []string      `json:"regions"`
+	Language           string        `json:"language"`
 }
 
 type XlsxEntry struct {
 	ItemID          string
 	Title           string
-	Authors         string
+	Authors         []string
+	AuthorsEtAl     bool
 	URL             string
 	OrgPublishers   []string
 	OrgDocID        string
@@ -107,15 +156,25 @@ type XlsxEntry struct {
 	Regions         []string
 	StartDate       string
 	EndDate         string
+	DatePrecision   DatePrecision
 	Language        string
 	rawLanguages    []string
 	AltLanguageIDs  []string
 	RelatedIDs      []string
+	Relations       []EntryRelation
+	// nil if the sheet doesn't have a previous IDs column
+	PreviousIDs *[]string
+	// blank if the sheet doesn't say
+	Visibility   EntryVisibility
+	EmbargoUntil string
+	// nil if the sheet doesn't have a notes column
+	Notes *string
 }
 
 func (newEntry *XlsxEntry) Matches(oldEntry Entry) bool {
 	return (newEntry.Title == oldEntry.Title &&
-		newEntry.Authors == oldEntry.Authors &&
+		slices.Equal(newEntry.Authors, oldEntry.Authors) &&
+		newEntry.AuthorsEtAl == oldEntry.AuthorsEtAl &&
 		newEntry.URL == oldEntry.URL &&
 		slices.Equal(newEntry.OrgPublishers, oldEntry.OrgPublishers) &&
 		newEntry.OrgDocID == oldEntry.OrgDocID &&
@@ -126,10 +185,85 @@ func (newEntry *XlsxEntry) Matches(oldEntry Entry) bool {
 		newEntry.YouthLedDetails == oldEntry.YouthLedDetails &&
 		slices.Equal(newEntry.Keywords, oldEntry.Keywords) &&
 		slices.Equal(newEntry.Regions, oldEntry.Regions) &&
-		// newEntry.StartDate == oldEntry.StartDate.Format("2006-01-02") &&
-		// newEntry.EndDate == oldEntry.EndDate.Format("2006-01-02") &&
+		newEntry.DatePrecision == oldEntry.DatePrecision &&
+		(newEntry.DatePrecision == DatePrecisionUnknown || (newEntry.StartDate == oldEntry.StartDate.Format(time.DateOnly) &&
+			newEntry.EndDate == oldEntry.EndDate.Format(time.DateOnly))) &&
 		newEntry.Language == oldEntry.Language &&
-		slices.Equal(newEntry.RelatedIDs, oldEntry.RelatedIDs))
+		slices.Equal(newEntry.RelatedIDs, oldEntry.RelatedIDs) &&
+		slices.Equal(newEntry.Relations, oldEntry.Relations) &&
+		(newEntry.PreviousIDs == nil || slices.Equal(*newEntry.PreviousIDs, oldEntry.PreviousIDs)) &&
+		(newEntry.Visibility == "" || (newEntry.Visibility == oldEntry.Visibility &&
+			newEntry.EmbargoUntil == formatOptionalDate(oldEntry.EmbargoUntil))) &&
+		(newEntry.Notes == nil || *newEntry.Notes == oldEntry.Notes))
+}
+
+func formatOptionalDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.Format(time.DateOnly)
+}
+
+// EntryTombstone is what's left of a deleted entry.
+type EntryTombstone struct {
+	ID         string    `json:"id"`
+	DeletedAt  time.Time `json:"deleted_at"`
+	Reason     string    `json:"reason"`
+	ReplacedBy string    `json:"replaced_by"`
+}
+
+// revisions
+
+// EntryChange says where a change to entries came from, and who made it.
+type EntryChange struct {
+	Source string `json:"source"`
+	Actor  string `json:"actor"`
+}
+
+type EntryRevision struct {
+	ID        int                `json:"id"`
+	EntryID   string             `json:"entry_id"`
+	ChangedAt time.Time          `json:"changed_at"`
+	Action    string             `json:"action"`
+	Source    string             `json:"source"`
+	Actor     string             `json:"actor"`
+	Changes   []EntryFieldChange `json:"changes"`
+}
+
+// EntryFieldChange is one field's value before and after a revision. Old is
+// nil for created entries and New is nil for deleted ones.
+type EntryFieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// organisations
+
+type Organisation struct {
+	ID      int      `json:"id"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+	OrgType string   `json:"org_type"`
+	Country string   `json:"country"`
+	Website string   `json:"website"`
+	Entries int      `json:"entries"`
+}
+
+// keywords
+
+type KeywordTerm struct {
+	ID          int      `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Synonyms    []string `json:"synonyms"`
+	BroaderID   *int     `json:"broader_id"`
+
+	// entries tagged with this term, and with this term or any narrower one
+	Entries      int `json:"entries"`
+	TotalEntries int `json:"total_entries"`
+
+	Narrower []KeywordTerm `json:"narrower"`
 }
 
 // others
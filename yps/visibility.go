@@ -0,0 +1,65 @@
+package yps
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type EntryVisibility string
+
+const (
+	EntryVisibilityPublished EntryVisibility = "published"
+	EntryVisibilityDraft     EntryVisibility = "draft"
+	EntryVisibilityEmbargoed EntryVisibility = "embargoed"
+	EntryVisibilityHidden    EntryVisibility = "hidden"
+)
+
+// publicEntriesFilter matches the entries that anonymous visitors can see.
+// Embargoed entries become public once their date arrives.
+const publicEntriesFilter = `(visibility = 'published' or (visibility = 'embargoed' and embargo_until <= current_date))`
+
+// visibleEntriesFilter returns the filter for the entries the caller can see.
+func visibleEntriesFilter(includeNonPublic bool) string {
+	if includeNonPublic {
+		return `true`
+	}
+	return publicEntriesFilter
+}
+
+// ParseVisibility reads a visibility column value like 'Draft' or 'Embargoed
+// until 2025-06-01'. A blank value gives a blank visibility, which leaves
+// existing entries as they are and publishes new ones.
+func ParseVisibility(input string) (visibility EntryVisibility, embargoUntil string, err error) {
+	input = strings.Join(strings.Fields(strings.ToLower(input)), " ")
+
+	switch EntryVisibility(input) {
+	case "":
+		return "", "", nil
+	case EntryVisibilityPublished, EntryVisibilityDraft, EntryVisibilityHidden:
+		return EntryVisibility(input), "", nil
+	}
+
+	rawDate, embargoed := strings.CutPrefix(input, "embargoed")
+	if !embargoed {
+		return "", "", fmt.Errorf("visibility '%s' must be one of Published, Draft, Hidden or 'Embargoed until YYYY-MM-DD'", input)
+	}
+	rawDate = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rawDate), "until"))
+	date, err := time.Parse(time.DateOnly, rawDate)
+	if err != nil {
+		return "", "", fmt.Errorf("embargoed entries need a date like 'Embargoed until 2025-06-01', could not read '%s'", rawDate)
+	}
+
+	return EntryVisibilityEmbargoed, date.Format(time.DateOnly), nil
+}
+
+// FormatVisibility is the reverse of ParseVisibility.
+func FormatVisibility(visibility EntryVisibility, embargoUntil *time.Time) string {
+	if visibility == EntryVisibilityEmbargoed && embargoUntil != nil {
+		return "Embargoed until " + embargoUntil.Format(time.DateOnly)
+	}
+	if visibility == "" {
+		return ""
+	}
+	return strings.ToUpper(string(visibility[:1])) + string(visibility[1:])
+}
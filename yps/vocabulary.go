@@ -0,0 +1,216 @@
+package yps
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type VocabularyField string
+
+const (
+	VocabularyFieldDocType  VocabularyField = "entry_type"
+	VocabularyFieldOrgType  VocabularyField = "org_type"
+	VocabularyFieldKeyword  VocabularyField = "keyword"
+	VocabularyFieldYouthLed VocabularyField = "youth_led"
+)
+
+var vocabularyFieldNames = map[VocabularyField]string{
+	VocabularyFieldDocType:  "document type",
+	VocabularyFieldOrgType:  "org type",
+	VocabularyFieldKeyword:  "keyword",
+	VocabularyFieldYouthLed: "youth-led status",
+}
+
+type VocabularyMatchType string
+
+const (
+	VocabularyMatchExact    VocabularyMatchType = "exact"
+	VocabularyMatchPrefix   VocabularyMatchType = "prefix"
+	VocabularyMatchContains VocabularyMatchType = "contains"
+)
+
+// VocabularyRule maps raw values of a field to a canonical value. Rules are
+// checked in priority order, lowest first, and the first one that matches wins.
+type VocabularyRule struct {
+	ID             int                 `json:"id"`
+	Field          VocabularyField     `json:"field"`
+	MatchType      VocabularyMatchType `json:"match_type"`
+	RawValue       string              `json:"raw_value"`
+	CanonicalValue string              `json:"canonical_value"`
+	Priority       int                 `json:"priority"`
+}
+
+// VocabularyNormalisation is a change made by a rule during an import.
+type VocabularyNormalisation struct {
+	Field VocabularyField `json:"field"`
+	From  string          `json:"from"`
+	To    string          `json:"to"`
+	Count int             `json:"count"`
+}
+
+// NormaliseVocabularyValue returns the form values are matched against rules
+// in, which ignores case and extra spaces.
+func NormaliseVocabularyValue(value string) string {
+	return strings.ToLower(strings.Join(strings.Fields(value), " "))
+}
+
+func (rule *VocabularyRule) Matches(field VocabularyField, normalisedValue string) bool {
+	if rule.Field != field {
+		return false
+	}
+	switch rule.MatchType {
+	case VocabularyMatchExact:
+		return normalisedValue == rule.RawValue
+	case VocabularyMatchPrefix:
+		return strings.HasPrefix(normalisedValue, rule.RawValue)
+	case VocabularyMatchContains:
+		return strings.Contains(normalisedValue, rule.RawValue)
+	}
+	return false
+}
+
+// ApplyVocabularyRules returns the canonical value for the given raw value, and
+// whether any rule matched it. rules must be in priority order.
+func ApplyVocabularyRules(rules []VocabularyRule, field VocabularyField, value string) (string, bool) {
+	normalisedValue := NormaliseVocabularyValue(value)
+	for _, rule := range rules {
+		if rule.Matches(field, normalisedValue) {
+			return rule.CanonicalValue, true
+		}
+	}
+	return value, false
+}
+
+func cleanVocabularyRule(rule VocabularyRule) (VocabularyRule, error) {
+	if _, exists := vocabularyFieldNames[rule.Field]; !exists {
+		return rule, fmt.Errorf("field must be one of: %s, %s, %s, %s", VocabularyFieldDocType, VocabularyFieldOrgType, VocabularyFieldKeyword, VocabularyFieldYouthLed)
+	}
+	if rule.MatchType == "" {
+		rule.MatchType = VocabularyMatchExact
+	}
+	if !slices.Contains([]VocabularyMatchType{VocabularyMatchExact, VocabularyMatchPrefix, VocabularyMatchContains}, rule.MatchType) {
+		return rule, fmt.Errorf("match type must be one of: %s, %s, %s", VocabularyMatchExact, VocabularyMatchPrefix, VocabularyMatchContains)
+	}
+
+	rule.RawValue = NormaliseVocabularyValue(rule.RawValue)
+	rule.CanonicalValue = strings.Join(strings.Fields(rule.CanonicalValue), " ")
+	if rule.RawValue == "" || rule.CanonicalValue == "" {
+		return rule, errors.New("raw and canonical values must be given")
+	}
+
+	return rule, nil
+}
+
+// handler
+
+type GetVocabularyRulesResponse struct {
+	Rules []VocabularyRule `json:"rules"`
+}
+
+func getVocabularyRules(c *gin.Context) {
+	rules, err := TheDb.GetVocabularyRules()
+	if err != nil {
+		fmt.Println("Could not get vocabulary rules:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not get vocabulary rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, GetVocabularyRulesResponse{
+		Rules: rules,
+	})
+}
+
+type VocabularyRuleRequest struct {
+	ID int `uri:"id" binding:"required"`
+}
+
+type EditVocabularyRuleParams struct {
+	Field          VocabularyField     `json:"field" binding:"required"`
+	MatchType      VocabularyMatchType `json:"match_type"`
+	RawValue       string              `json:"raw_value" binding:"required"`
+	CanonicalValue string              `json:"canonical_value" binding:"required"`
+	Priority       *int                `json:"priority"`
+}
+
+func saveVocabularyRuleFromParams(c *gin.Context, id int) {
+	var params EditVocabularyRuleParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule := VocabularyRule{
+		ID:             id,
+		Field:          params.Field,
+		MatchType:      params.MatchType,
+		RawValue:       params.RawValue,
+		CanonicalValue: params.CanonicalValue,
+		Priority:       100,
+	}
+	if params.Priority != nil {
+		rule.Priority = *params.Priority
+	}
+
+	rule, err := cleanVocabularyRule(rule)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule.ID, err = TheDb.SaveVocabularyRule(rule)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Vocabulary rule not found"})
+		return
+	} else if err != nil {
+		fmt.Println("Could not save vocabulary rule:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not save vocabulary rule, check that it isn't a duplicate"})
+		return
+	}
+
+	Log(LogLevelInfo, "vocabulary-update", fmt.Sprintf("Updated %s rule for '%s'", rule.Field, rule.RawValue), rule)
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "rule": rule})
+}
+
+func createVocabularyRule(c *gin.Context) {
+	saveVocabularyRuleFromParams(c, 0)
+}
+
+func editVocabularyRule(c *gin.Context) {
+	var req VocabularyRuleRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		fmt.Println("Could not get vocabulary rule URI binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Rule must be given"})
+		return
+	}
+
+	saveVocabularyRuleFromParams(c, req.ID)
+}
+
+func deleteVocabularyRule(c *gin.Context) {
+	var req VocabularyRuleRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		fmt.Println("Could not get vocabulary rule URI binding:", err.Error())
+		c.JSON(400, gin.H{"error": "Rule must be given"})
+		return
+	}
+
+	err := TheDb.DeleteVocabularyRule(req.ID)
+	if err != nil {
+		fmt.Println("Could not delete vocabulary rule:", err.Error())
+		c.JSON(400, gin.H{"error": "Could not delete vocabulary rule"})
+		return
+	}
+
+	Log(LogLevelInfo, "vocabulary-delete", fmt.Sprintf("Deleted vocabulary rule %d", req.ID), map[string]int{
+		"rule": req.ID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
@@ -5,7 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"strconv"
+	"slices"
 	"strings"
 	"time"
 
@@ -15,9 +15,10 @@ import (
 )
 
 type EntriesXLSX struct {
-	file    *xlsxreader.XlsxFile
-	Entries map[string]XlsxEntry
-	Nits    []string
+	file           *xlsxreader.XlsxFile
+	Entries        map[string]XlsxEntry
+	Nits           []string
+	Normalisations []VocabularyNormalisation
 }
 
 func simplifyColumnName(input string) string {
@@ -43,35 +44,37 @@ func getCellValue(row xlsxreader.Row, column string) string {
 	return ""
 }
 
-func parseDayFromString(input string) (day string, err error) {
-	for _, sub := range strings.Split(input, " ") {
-		num, err := strconv.Atoi(sub)
-
-		if err == nil && num > 0 && num < 31 {
-			day = strconv.Itoa(num)
-			return day, nil
-		}
-	}
-
-	return day, errors.New("Could not parse day")
+// ImportLookups are what ReadEntriesFile checks the sheet's values against.
+type ImportLookups struct {
+	// Entries are the existing entries that related and alternate IDs can
+	// point to, after the sheet itself. Can be nil.
+	Entries map[string]Entry
+	// OrgNames maps normalised organisation names and aliases to canonical
+	// names, see NormaliseOrgName. Orgs aren't checked if this is nil.
+	OrgNames map[string]string
+	// VocabularyRules normalise document types, org types and keywords, and
+	// classify the youth-led column. Must be in priority order.
+	VocabularyRules []VocabularyRule
+	// RelationTypes are the typed relationships that can be used in the
+	// related column, by name.
+	RelationTypes map[string]RelationType
+	// KeywordTerms maps the normalised names and synonyms of keyword terms to
+	// their names. It's checked after VocabularyRules. Can be nil.
+	KeywordTerms map[string]string
+	// KnownValues are the values of each vocabulary field already in use.
+	// Values that aren't in here are flagged, unless this is nil.
+	KnownValues map[VocabularyField]map[string]bool
 }
 
-var monthNameToNumber = map[string]int{
-	"january":   1,
-	"february":  2,
-	"march":     3,
-	"april":     4,
-	"may":       5,
-	"june":      6,
-	"july":      7,
-	"august":    8,
-	"september": 9,
-	"october":   10,
-	"november":  11,
-	"december":  12,
+// ReadEntriesOptions change how ReadEntriesFile fixes up the sheet.
+type ReadEntriesOptions struct {
+	// SymmetriseLinks adds missing back-links to related and alternate items
+	// in the sheet, instead of only reporting them.
+	SymmetriseLinks bool `json:"symmetrise_links"`
 }
 
-func ReadEntriesFile(input io.Reader) (*EntriesXLSX, error) {
+// ReadEntriesFile reads the given spreadsheet.
+func ReadEntriesFile(input io.Reader, lookups ImportLookups, options ReadEntriesOptions) (*EntriesXLSX, error) {
 	buf := new(bytes.Buffer)
 	buf.ReadFrom(input)
 
@@ -100,6 +103,8 @@ func ReadEntriesFile(input io.Reader) (*EntriesXLSX, error) {
 	// read entries columns
 	cols := make(map[ypsc.ColumnType]string)
 
+	reader := newEntriesReader(&entries, lookups, options)
+
 	for row := range file.ReadRows(file.Sheets[sheetToUse]) {
 		if row.Error != nil {
 			return nil, fmt.Errorf("error on row [%d]: %s", row.Index, row.Error.Error())
@@ -165,6 +170,12 @@ func ReadEntriesFile(input io.Reader) (*EntriesXLSX, error) {
 					thisColumnType = ypsc.RegionGlobal
 				} else if name == "n/a" {
 					thisColumnType = ypsc.RegionNA
+				} else if name == "previous ids" {
+					thisColumnType = ypsc.PreviousIDs
+				} else if name == "visibility" {
+					thisColumnType = ypsc.Visibility
+				} else if name == "notes" {
+					thisColumnType = ypsc.Notes
 				}
 
 				if thisColumnType != ypsc.None {
@@ -184,6 +195,9 @@ func ReadEntriesFile(input io.Reader) (*EntriesXLSX, error) {
 			if len(missingColumnTypes) > 0 {
 				return nil, errors.New("Cannot find columns: " + ypsc.ColumnNames(missingColumnTypes...))
 			}
+			for _, columnType := range ypsc.OptionalColumns {
+				_, reader.optionalColumns[columnType] = cols[columnType]
+			}
 
 			continue
 		}
@@ -200,200 +214,367 @@ func ReadEntriesFile(input io.Reader) (*EntriesXLSX, error) {
 			return nil, fmt.Errorf("duplicate item ID found on row %d: %s", row.Index, itemID)
 		}
 
-		// simple columns
-		var title = strings.TrimSpace(getCellValue(row, cols[ypsc.Title]))
-		var authors = strings.TrimSpace(getCellValue(row, cols[ypsc.Authors]))
-		var orgdocid = strings.TrimSpace(getCellValue(row, cols[ypsc.DocNumber]))
-		var url = strings.TrimSpace(getCellValue(row, cols[ypsc.URL]))
-		var abstract = strings.TrimSpace(getCellValue(row, cols[ypsc.Abstract]))
-		var youthleddesc = strings.TrimSpace(getCellValue(row, cols[ypsc.YouthInvolvement]))
-		var orgtype = strings.TrimSpace(getCellValue(row, cols[ypsc.OrgType]))
-		var doctype = strings.TrimSpace(getCellValue(row, cols[ypsc.DocType]))
+		newEntry, err := reader.readEntry(itemID, func(columnType ypsc.ColumnType) string {
+			return getCellValue(row, cols[columnType])
+		})
+		if err != nil {
+			return nil, err
+		}
+		entries.Entries[itemID] = newEntry
+	}
 
-		var orgpublishers = trimSpacesOnItemsSkipZero(strings.Split(getCellValue(row, cols[ypsc.OrgPublisher]), ";"))
-		var keywords = trimSpacesOnItemsSkipZero(strings.Split(getCellValue(row, cols[ypsc.Keywords]), ";"))
-		var altlangIDs = trimSpacesOnItemsSkipZero(strings.Split(getCellValue(row, cols[ypsc.AlternateLanguageEntries]), ","))
-		var relatedIDs = trimSpacesOnItemsSkipZero(strings.Split(getCellValue(row, cols[ypsc.RelatedEntries]), ","))
+	err = reader.finish()
+	if err != nil {
+		return nil, err
+	}
 
-		// doc number needs to be removed if N/A
-		var docnumber = strings.TrimSpace(getCellValue(row, cols[ypsc.DocNumber]))
-		if docnumber == "N/A" {
-			docnumber = ""
-		}
+	return &entries, nil
+}
 
-		// work out whether the description is youth led or not
-		youthled := "Unknown"
-		if strings.Contains(strings.ToLower(youthleddesc), "co-authored") || strings.Contains(strings.ToLower(youthleddesc), "co authored") {
-			// this check is before No, because "No, co-authored with adults" and "Yes, co-authored with adults" should both be Co-authored
-			youthled = "Co-authored"
-		} else if strings.HasPrefix(strings.ToLower(youthleddesc), "yes") || strings.HasPrefix(strings.ToLower(youthleddesc), "youth-led") {
-			youthled = "Yes"
-		} else if strings.HasPrefix(strings.ToLower(youthleddesc), "no") {
-			youthled = "No"
-		} else if strings.HasPrefix(strings.ToLower(youthleddesc), "n/a") {
-			youthled = "N/A"
-		}
-		if youthled == "Unknown" {
-			entries.Nits = append(entries.Nits, fmt.Sprintf("[Item %s] Could not work out the 'youth-led' status, please make it start with 'Yes' or 'No', or include the text 'Co-authored'.", itemID))
-		}
+// ReadEntryColumns reads a single entry from its raw column values, following
+// the same rules as ReadEntriesFile. Columns that aren't given are blank.
+func ReadEntryColumns(itemID string, columns map[ypsc.ColumnType]string, lookups ImportLookups, options ReadEntriesOptions) (*EntriesXLSX, error) {
+	var entries EntriesXLSX
+	entries.Entries = make(map[string]XlsxEntry)
+	reader := newEntriesReader(&entries, lookups, options)
+	for _, columnType := range ypsc.OptionalColumns {
+		_, reader.optionalColumns[columnType] = columns[columnType]
+	}
 
-		// regions
-		var regions []string
-		if getCellValue(row, cols[ypsc.RegionEastSouthAfrica]) == "1" {
-			regions = append(regions, "East and Southern Africa")
-		}
-		if getCellValue(row, cols[ypsc.RegionEastCentralAsia]) == "1" {
-			regions = append(regions, "East and Central Asia")
-		}
-		if getCellValue(row, cols[ypsc.RegionSouthEastAsiaPacific]) == "1" {
-			regions = append(regions, "Southeast Asia and the Pacific")
-		}
-		if getCellValue(row, cols[ypsc.RegionEuropeEurasia]) == "1" {
-			regions = append(regions, "Europe and Eurasia")
+	if strings.TrimSpace(columns[ypsc.Title]) == "" {
+		return nil, fmt.Errorf("item %s must have a title", itemID)
+	}
+
+	newEntry, err := reader.readEntry(itemID, func(columnType ypsc.ColumnType) string {
+		return columns[columnType]
+	})
+	if err != nil {
+		return nil, err
+	}
+	entries.Entries[itemID] = newEntry
+
+	err = reader.finish()
+	if err != nil {
+		return nil, err
+	}
+
+	return &entries, nil
+}
+
+// entriesReader turns the column values of each entry into an XlsxEntry, and
+// then checks the entries against each other and the lookups once they've all
+// been read. It's shared by spreadsheet imports and single-entry edits, so
+// they follow the same rules.
+type entriesReader struct {
+	entries *EntriesXLSX
+	lookups ImportLookups
+	options ReadEntriesOptions
+
+	// which optional columns were given, so missing ones can leave existing values alone
+	optionalColumns map[ypsc.ColumnType]bool
+
+	// vocabulary normalisation, and values we haven't seen before along with the items they're on
+	normalisationCounts map[VocabularyNormalisation]int
+	unseenValues        map[VocabularyField]map[string][]string
+}
+
+func newEntriesReader(entries *EntriesXLSX, lookups ImportLookups, options ReadEntriesOptions) *entriesReader {
+	return &entriesReader{
+		entries:             entries,
+		lookups:             lookups,
+		options:             options,
+		optionalColumns:     make(map[ypsc.ColumnType]bool),
+		normalisationCounts: make(map[VocabularyNormalisation]int),
+		unseenValues:        make(map[VocabularyField]map[string][]string),
+	}
+}
+
+func (r *entriesReader) normaliseValue(itemID string, field VocabularyField, value string) string {
+	if value == "" {
+		return value
+	}
+	canonicalValue, matched := ApplyVocabularyRules(r.lookups.VocabularyRules, field, value)
+	if !matched && field == VocabularyFieldKeyword {
+		canonicalValue, matched = r.lookups.KeywordTerms[NormaliseVocabularyValue(value)]
+		if !matched {
+			canonicalValue = value
 		}
-		if getCellValue(row, cols[ypsc.RegionLatinAmericaCaribbean]) == "1" {
-			regions = append(regions, "Latin America and the Caribbean")
+	}
+	if canonicalValue != value {
+		r.normalisationCounts[VocabularyNormalisation{Field: field, From: value, To: canonicalValue}] += 1
+	}
+	if !matched && r.lookups.KnownValues != nil && !r.lookups.KnownValues[field][canonicalValue] {
+		if r.unseenValues[field] == nil {
+			r.unseenValues[field] = make(map[string][]string)
 		}
-		if getCellValue(row, cols[ypsc.RegionMiddleEastNorthAfrica]) == "1" {
-			regions = append(regions, "Middle East and North Africa")
+		r.unseenValues[field][canonicalValue] = append(r.unseenValues[field][canonicalValue], itemID)
+	}
+	return canonicalValue
+}
+
+// readEntry reads a single entry, getting each column's raw value from value.
+func (r *entriesReader) readEntry(itemID string, value func(columnType ypsc.ColumnType) string) (XlsxEntry, error) {
+	// simple columns
+	var title = strings.TrimSpace(value(ypsc.Title))
+	var rawAuthors = strings.TrimSpace(value(ypsc.Authors))
+	var orgdocid = strings.TrimSpace(value(ypsc.DocNumber))
+	var url = strings.TrimSpace(value(ypsc.URL))
+	var abstract = strings.TrimSpace(value(ypsc.Abstract))
+	var youthleddesc = strings.TrimSpace(value(ypsc.YouthInvolvement))
+	var orgtype = r.normaliseValue(itemID, VocabularyFieldOrgType, strings.TrimSpace(value(ypsc.OrgType)))
+	var doctype = r.normaliseValue(itemID, VocabularyFieldDocType, strings.TrimSpace(value(ypsc.DocType)))
+
+	var orgpublishers = trimSpacesOnItemsSkipZero(strings.Split(value(ypsc.OrgPublisher), ";"))
+	var keywords []string
+	for _, keyword := range trimSpacesOnItemsSkipZero(strings.Split(value(ypsc.Keywords), ";")) {
+		keyword = r.normaliseValue(itemID, VocabularyFieldKeyword, keyword)
+		if !slices.Contains(keywords, keyword) {
+			keywords = append(keywords, keyword)
 		}
-		if getCellValue(row, cols[ypsc.RegionNorthAmerica]) == "1" {
-			regions = append(regions, "North America")
+	}
+	var altlangIDs = trimSpacesOnItemsSkipZero(strings.Split(value(ypsc.AlternateLanguageEntries), ","))
+
+	// related items are plain IDs, or typed like 'supersedes:123'
+	var relatedIDs []string
+	var relations []EntryRelation
+	for _, item := range trimSpacesOnItemsSkipZero(strings.Split(value(ypsc.RelatedEntries), ",")) {
+		relationType, relatedID := parseRelatedItem(item)
+		if relationType == plainRelationType {
+			relatedIDs = append(relatedIDs, relatedID)
+			continue
 		}
-		if getCellValue(row, cols[ypsc.RegionSouthAsia]) == "1" {
-			regions = append(regions, "South Asia")
+		if _, known := r.lookups.RelationTypes[relationType]; !known {
+			return XlsxEntry{}, fmt.Errorf("item %s lists [%s] as a related item, but [%s] is not a known relationship type", itemID, item, relationType)
 		}
-		if getCellValue(row, cols[ypsc.RegionWestCentralAfrica]) == "1" {
-			regions = append(regions, "West and Central Africa")
+		if relatedID == itemID {
+			r.entries.Nits = append(r.entries.Nits, fmt.Sprintf("[Item %s] Lists itself as '%s', ignoring it.", itemID, relationType))
+			continue
 		}
-		if getCellValue(row, cols[ypsc.RegionGlobal]) == "1" {
-			regions = append(regions, "Global")
+		relation := EntryRelation{Type: relationType, ID: relatedID}
+		if !slices.Contains(relations, relation) {
+			relations = append(relations, relation)
 		}
-		if getCellValue(row, cols[ypsc.RegionNA]) == "1" {
-			regions = append(regions, "N/A")
+	}
+	sortRelations(relations)
+
+	// old IDs that should lead to this entry
+	var previousIDs []string
+	for _, previousID := range trimSpacesOnItemsSkipZero(strings.Split(value(ypsc.PreviousIDs), ",")) {
+		if previousID == itemID {
+			r.entries.Nits = append(r.entries.Nits, fmt.Sprintf("[Item %s] Lists itself as a previous ID, ignoring it.", itemID))
+			continue
 		}
-		if len(regions) < 1 {
-			regions = append(regions, "N/A")
-			entries.Nits = append(entries.Nits, fmt.Sprintf("[Item %s] No regions defined, marking as N/A.", itemID))
+		if !slices.Contains(previousIDs, previousID) {
+			previousIDs = append(previousIDs, previousID)
 		}
+	}
+	slices.SortFunc(previousIDs, compareItemIDs)
 
-		// languages need special handling
-		var langs []string
-		for _, languageName := range strings.Split(getCellValue(row, cols[ypsc.Languages]), ",") {
-			languageName = strings.TrimSpace(languageName)
-			if languageName == "" {
-				continue
+	// who can see the entry
+	visibility, embargoUntil, err := ParseVisibility(value(ypsc.Visibility))
+	if err != nil {
+		return XlsxEntry{}, fmt.Errorf("item %s: %s", itemID, err.Error())
+	}
+	if visibility == EntryVisibilityEmbargoed && embargoUntil <= time.Now().Format(time.DateOnly) {
+		r.entries.Nits = append(r.entries.Nits, fmt.Sprintf("[Item %s] Embargo date %s has already arrived, so the entry will be public.", itemID, embargoUntil))
+	}
+
+	// orgs are stored under their canonical names
+	if r.lookups.OrgNames != nil {
+		var resolvedOrgs []string
+		for _, org := range orgpublishers {
+			canonicalName, known := r.lookups.OrgNames[NormaliseOrgName(org)]
+			if !known {
+				r.entries.Nits = append(r.entries.Nits, fmt.Sprintf("[Item %s] Unknown organisation/publisher '%s', please add it or an alias for it to the organisations list.", itemID, org))
+				canonicalName = org
 			}
-			languageCode, err := ypsl.GetCode(languageName)
-			if err != nil {
-				return nil, fmt.Errorf("language error on item %s: %s", itemID, err.Error())
-			}
-			langs = append(langs, languageCode)
-		}
-
-		// start and end dates
-		var startDate, endDate string
-		rawYear := strings.TrimSpace(getCellValue(row, cols[ypsc.Year]))
-		rawDayMonth := strings.TrimSpace(getCellValue(row, cols[ypsc.DayMonth]))
-		if rawYear != "N/A" {
-			// try scanning the time. this is a quirk with how dates are entered in the db file
-			t, basicScanErr := time.Parse("2006-01-02", rawDayMonth)
-
-			if basicScanErr == nil {
-				startDate = fmt.Sprintf("%s-%s", rawYear, t.Format("01-02"))
-			} else if rawDayMonth == "N/A" {
-				startDate = fmt.Sprintf("%s-01-01", rawYear)
-			} else if monthNameToNumber[strings.ToLower(rawDayMonth)] != 0 {
-				startDate = fmt.Sprintf("%s-%d-01", rawYear, monthNameToNumber[strings.ToLower(rawDayMonth)])
-			} else {
-				monthList := strings.Split(strings.ToLower(rawDayMonth), "-")
-				if len(monthList) == 2 {
-					var startMonth, endMonth string
-					startDay := "01"
-					endDay := "01"
-
-					// parse month
-					for monthName, monthNumber := range monthNameToNumber {
-						if strings.Contains(monthList[0], monthName) {
-							startMonth = strconv.Itoa(monthNumber)
-						}
-						if strings.Contains(monthList[1], monthName) {
-							endMonth = strconv.Itoa(monthNumber)
-						}
-
-						if startMonth == "" && endMonth != "" {
-							startMonth = endMonth
-						}
-						if endMonth == "" && startMonth != "" {
-							endMonth = startMonth
-						}
-					}
-
-					// parse day
-					parsedDay, err := parseDayFromString(monthList[0])
-					if err == nil {
-						startDay = parsedDay
-					}
-
-					parsedDay, err = parseDayFromString(monthList[1])
-					if err == nil {
-						endDay = parsedDay
-					} else if startMonth == endMonth {
-						endDay = startDay
-					}
-
-					if startMonth != "" && endMonth != "" {
-						startDate = fmt.Sprintf("%s-%s-%s", rawYear, startMonth, startDay)
-						endDate = fmt.Sprintf("%s-%s-%s", rawYear, endMonth, endDay)
-					}
-				}
+			if !slices.Contains(resolvedOrgs, canonicalName) {
+				resolvedOrgs = append(resolvedOrgs, canonicalName)
 			}
+		}
+		orgpublishers = resolvedOrgs
+	}
 
-			if startDate == "" {
-				fmt.Println("can't process", rawDayMonth, "- skipping this date for the import")
-				startDate = fmt.Sprintf("%s-01-01", rawYear)
-				entries.Nits = append(entries.Nits, fmt.Sprintf("[Item %s] Could not work out the start/end dates.", itemID))
-			}
+	// doc number needs to be removed if N/A
+	var docnumber = strings.TrimSpace(value(ypsc.DocNumber))
+	if docnumber == "N/A" {
+		docnumber = ""
+	}
 
-			if endDate == "" {
-				endDate = startDate
-			}
+	// work out whether the description is youth led or not
+	youthled := "Unknown"
+	if classification, matched := ApplyVocabularyRules(r.lookups.VocabularyRules, VocabularyFieldYouthLed, youthleddesc); matched {
+		youthled = classification
+	}
+	if youthled == "Unknown" {
+		r.entries.Nits = append(r.entries.Nits, fmt.Sprintf("[Item %s] Could not work out the 'youth-led' status, please make it start with 'Yes' or 'No', or include the text 'Co-authored'.", itemID))
+	}
+
+	// authors
+	authors, authorsEtAl := SplitAuthors(rawAuthors)
+	if len(authors) == 1 && strings.Count(authors[0], ",") > 1 {
+		r.entries.Nits = append(r.entries.Nits, fmt.Sprintf("[Item %s] Authors look like they might be separated by commas, which isn't supported. Please separate them with semicolons.", itemID))
+	}
+
+	// regions
+	var regions []string
+	if value(ypsc.RegionEastSouthAfrica) == "1" {
+		regions = append(regions, "East and Southern Africa")
+	}
+	if value(ypsc.RegionEastCentralAsia) == "1" {
+		regions = append(regions, "East and Central Asia")
+	}
+	if value(ypsc.RegionSouthEastAsiaPacific) == "1" {
+		regions = append(regions, "Southeast Asia and the Pacific")
+	}
+	if value(ypsc.RegionEuropeEurasia) == "1" {
+		regions = append(regions, "Europe and Eurasia")
+	}
+	if value(ypsc.RegionLatinAmericaCaribbean) == "1" {
+		regions = append(regions, "Latin America and the Caribbean")
+	}
+	if value(ypsc.RegionMiddleEastNorthAfrica) == "1" {
+		regions = append(regions, "Middle East and North Africa")
+	}
+	if value(ypsc.RegionNorthAmerica) == "1" {
+		regions = append(regions, "North America")
+	}
+	if value(ypsc.RegionSouthAsia) == "1" {
+		regions = append(regions, "South Asia")
+	}
+	if value(ypsc.RegionWestCentralAfrica) == "1" {
+		regions = append(regions, "West and Central Africa")
+	}
+	if value(ypsc.RegionGlobal) == "1" {
+		regions = append(regions, "Global")
+	}
+	if value(ypsc.RegionNA) == "1" {
+		regions = append(regions, "N/A")
+	}
+	if len(regions) < 1 {
+		regions = append(regions, "N/A")
+		r.entries.Nits = append(r.entries.Nits, fmt.Sprintf("[Item %s] No regions defined, marking as N/A.", itemID))
+	}
+
+	// languages need special handling
+	var langs []string
+	for _, languageName := range strings.Split(value(ypsc.Languages), ",") {
+		languageName = strings.TrimSpace(languageName)
+		if languageName == "" {
+			continue
 		}
+		languageCode, err := ypsl.GetCode(languageName)
+		if err != nil {
+			return XlsxEntry{}, fmt.Errorf("language error on item %s: %s", itemID, err.Error())
+		}
+		langs = append(langs, languageCode)
+	}
+
+	// start and end dates
+	rawYear := strings.TrimSpace(value(ypsc.Year))
+	rawDayMonth := strings.TrimSpace(value(ypsc.DayMonth))
+	date, err := ParseEntryDate(rawYear, rawDayMonth)
+	if err != nil {
+		r.entries.Nits = append(r.entries.Nits, fmt.Sprintf("[Item %s] Could not work out the start/end dates (%s), using '%s' precision.", itemID, err.Error(), date.Precision))
+	}
+	var startDate, endDate string
+	if date.Precision != DatePrecisionUnknown {
+		startDate = date.Start.Format(time.DateOnly)
+		endDate = date.End.Format(time.DateOnly)
+	}
+
+	newEntry := XlsxEntry{
+		ItemID:          itemID,
+		Title:           title,
+		Authors:         authors,
+		AuthorsEtAl:     authorsEtAl,
+		URL:             url,
+		OrgPublishers:   orgpublishers,
+		OrgDocID:        orgdocid,
+		OrgType:         orgtype,
+		DocType:         doctype,
+		Abstract:        abstract,
+		YouthLed:        youthled,
+		YouthLedDetails: youthleddesc,
+		Keywords:        keywords,
+		StartDate:       startDate,
+		EndDate:         endDate,
+		DatePrecision:   date.Precision,
+		Regions:         regions,
+		rawLanguages:    langs,
+		AltLanguageIDs:  altlangIDs,
+		RelatedIDs:      relatedIDs,
+		Relations:       relations,
+		Visibility:      visibility,
+		EmbargoUntil:    embargoUntil,
+	}
+	if r.optionalColumns[ypsc.PreviousIDs] {
+		newEntry.PreviousIDs = &previousIDs
+	}
+	if r.optionalColumns[ypsc.Notes] {
+		notes := strings.TrimSpace(value(ypsc.Notes))
+		newEntry.Notes = &notes
+	}
+	if len(langs) == 1 {
+		newEntry.Language = langs[0]
+	}
+	return newEntry, nil
+}
 
-		// insert into our list of processed entries
-		newEntry := XlsxEntry{
-			ItemID:          itemID,
-			Title:           title,
-			Authors:         authors,
-			URL:             url,
-			OrgPublishers:   orgpublishers,
-			OrgDocID:        orgdocid,
-			OrgType:         orgtype,
-			DocType:         doctype,
-			Abstract:        abstract,
-			YouthLed:        youthled,
-			YouthLedDetails: youthleddesc,
-			Keywords:        keywords,
-			StartDate:       startDate,
-			EndDate:         endDate,
-			Regions:         regions,
-			rawLanguages:    langs,
-			AltLanguageIDs:  altlangIDs,
-			RelatedIDs:      relatedIDs,
-		}
-		if len(langs) == 1 {
-			newEntry.Language = langs[0]
+// finish reports on vocabulary, works out languages and alternates, and checks
+// that links between entries make sense.
+func (r *entriesReader) finish() error {
+	// report vocabulary changes and new values
+	for normalisation, count := range r.normalisationCounts {
+		normalisation.Count = count
+		r.entries.Normalisations = append(r.entries.Normalisations, normalisation)
+	}
+	slices.SortFunc(r.entries.Normalisations, func(a, b VocabularyNormalisation) int {
+		if a.Field != b.Field {
+			return strings.Compare(string(a.Field), string(b.Field))
+		}
+		return strings.Compare(a.From, b.From)
+	})
+
+	var unseenNits []string
+	for field, values := range r.unseenValues {
+		for value, itemIDs := range values {
+			itemsLabel := "Item"
+			if len(itemIDs) > 1 {
+				itemsLabel = "Items"
+			}
+			nit := fmt.Sprintf("[%s %s] New %s '%s'.", itemsLabel, strings.Join(itemIDs, ", "), vocabularyFieldNames[field], value)
+			for knownValue := range r.lookups.KnownValues[field] {
+				if NormaliseVocabularyValue(knownValue) == NormaliseVocabularyValue(value) {
+					nit += fmt.Sprintf(" This looks like the existing '%s', add a vocabulary rule if they should be the same.", knownValue)
+					break
+				}
+			}
+			unseenNits = append(unseenNits, nit)
 		}
-		entries.Entries[itemID] = newEntry
 	}
+	slices.Sort(unseenNits)
+	r.entries.Nits = append(r.entries.Nits, unseenNits...)
 
 	// post-processing
-	for id, entry := range entries.Entries {
+	for id, entry := range r.entries.Entries {
 		// confirm related documents exist
 		for _, altID := range entry.RelatedIDs {
-			_, exists := entries.Entries[altID]
+			_, exists := r.entries.Entries[altID]
 			if !exists {
-				return nil, fmt.Errorf("item %s lists [%s] as a related item, but item [%s] does not exist", id, altID, altID)
+				_, exists = r.lookups.Entries[altID]
+			}
+			if !exists {
+				return fmt.Errorf("item %s lists [%s] as a related item, but item [%s] does not exist", id, altID, altID)
+			}
+		}
+		for _, relation := range entry.Relations {
+			_, exists := r.entries.Entries[relation.ID]
+			if !exists {
+				_, exists = r.lookups.Entries[relation.ID]
+			}
+			if !exists {
+				return fmt.Errorf("item %s lists [%s:%s] as a related item, but item [%s] does not exist", id, relation.Type, relation.ID, relation.ID)
 			}
 		}
 
@@ -410,15 +591,22 @@ func ReadEntriesFile(input io.Reader) (*EntriesXLSX, error) {
 				continue
 			}
 
-			altEntry, exists := entries.Entries[altID]
-			if !exists {
-				return nil, fmt.Errorf("item %s lists %s as an alternate language, but item %s does not exist", id, altID, altID)
+			var altLanguage string
+			altEntry, exists := r.entries.Entries[altID]
+			if exists {
+				altLanguage = altEntry.Language
+			} else {
+				existingEntry, existsInDb := r.lookups.Entries[altID]
+				if !existsInDb {
+					return fmt.Errorf("item %s lists %s as an alternate language, but item %s does not exist", id, altID, altID)
+				}
+				altLanguage = existingEntry.Language
 			}
-			if altEntry.Language == "" {
-				return nil, fmt.Errorf("item %s is an alternate, and must have only a single language defined", altID)
+			if altLanguage == "" {
+				return fmt.Errorf("item %s is an alternate, and must have only a single language defined", altID)
 			}
 			allAlternates = append(allAlternates, altID)
-			languagesToRemove[altEntry.Language] = true
+			languagesToRemove[altLanguage] = true
 		}
 
 		var finalLanguages []string
@@ -430,7 +618,7 @@ func ReadEntriesFile(input io.Reader) (*EntriesXLSX, error) {
 		}
 
 		if len(finalLanguages) != 1 {
-			return nil, fmt.Errorf("cannot work out which language item %s is, please confirm the alternates list is correct", id)
+			return fmt.Errorf("cannot work out which language item %s is, please confirm the alternates list is correct", id)
 		}
 
 		entry.Language = finalLanguages[0]
@@ -444,18 +632,177 @@ func ReadEntriesFile(input io.Reader) (*EntriesXLSX, error) {
 				continue
 			}
 
-			altEntry, exists := entries.Entries[altID]
+			altEntry, exists := r.entries.Entries[altID]
 			if !exists {
-				return nil, fmt.Errorf("item %s lists %s as an alternate language, but item %s does not exist", id, altID, altID)
+				// this one's only in the db, and only the sheet's entries get written
+				if !slices.Contains(r.lookups.Entries[altID].AltLanguageIDs, id) {
+					r.entries.Nits = append(r.entries.Nits, fmt.Sprintf("[Item %s] Alternate item %s is only in the database, and won't be updated to list this item as an alternate.", id, altID))
+				}
+				continue
 			}
 
 			altEntry.AltLanguageIDs = allAlternates
-			entries.Entries[altEntry.ItemID] = altEntry
+			r.entries.Entries[altEntry.ItemID] = altEntry
 		}
 
 		// post processing finished for this item, hooray
+		r.entries.Entries[id] = entry
+	}
+
+	checkLinkConsistency(r.entries, r.lookups, r.options)
+	r.checkDetectedLanguages()
+
+	return r.checkPreviousIDs()
+}
+
+// below this, detected languages that disagree with the entry are too unsure to mention
+const languageMismatchConfidence = 0.15
+
+// detectLanguageMismatch returns the language the title and abstract look
+// like they're in, and how confident it is that they're not in the declared
+// one. Languages the detector doesn't know aren't checked.
+func detectLanguageMismatch(title, abstract, declared string) (detected string, confidence float64, mismatch bool) {
+	if !ypsl.Detectable(declared) {
+		return "", 0, false
+	}
+	text := title + "\n" + abstract
+	detected, _ = ypsl.Detect(text)
+	if detected == "" || detected == declared {
+		return detected, 0, false
+	}
+
+	// measured against the declared language rather than the next closest,
+	// since close relatives like Spanish and Portuguese would otherwise hide it
+	scores := ypsl.Scores(text)
+	confidence = (scores[detected] - scores[declared]) / scores[detected]
+	return detected, confidence, confidence >= languageMismatchConfidence
+}
+
+// checkDetectedLanguages reports entries whose text looks like it's in a
+// different language to the one they're marked as.
+func (r *entriesReader) checkDetectedLanguages() {
+	var ids []string
+	for id := range r.entries.Entries {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, compareItemIDs)
+
+	for _, id := range ids {
+		entry := r.entries.Entries[id]
+		detected, confidence, mismatch := detectLanguageMismatch(entry.Title, entry.Abstract, entry.Language)
+		if mismatch {
+			r.entries.Nits = append(r.entries.Nits, fmt.Sprintf("[Item %s] Marked as %s, but the title and abstract look like %s (confidence %.2f).", id, ypsl.GetName(entry.Language), ypsl.GetName(detected), confidence))
+		}
+	}
+}
+
+// checkPreviousIDs makes sure each previous ID only leads to one entry, and
+// isn't also the ID of an entry in the sheet. Previous IDs can't be taken
+// from database entries that the sheet doesn't set previous IDs for.
+func (r *entriesReader) checkPreviousIDs() error {
+	var ids []string
+	for id := range r.entries.Entries {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, compareItemIDs)
+
+	keptBy := make(map[string]string)
+	for id, entry := range r.lookups.Entries {
+		if sheetEntry, inSheet := r.entries.Entries[id]; inSheet && sheetEntry.PreviousIDs != nil {
+			continue
+		}
+		for _, previousID := range entry.PreviousIDs {
+			keptBy[previousID] = id
+		}
+	}
+
+	claimedBy := make(map[string]string)
+	for _, id := range ids {
+		if r.entries.Entries[id].PreviousIDs == nil {
+			continue
+		}
+		for _, previousID := range *r.entries.Entries[id].PreviousIDs {
+			if _, exists := r.entries.Entries[previousID]; exists {
+				return fmt.Errorf("item %s lists [%s] as a previous ID, but item [%s] is still in the sheet", id, previousID, previousID)
+			}
+			if otherID, claimed := claimedBy[previousID]; claimed {
+				return fmt.Errorf("items %s and %s both list [%s] as a previous ID", otherID, id, previousID)
+			}
+			if otherID, kept := keptBy[previousID]; kept && otherID != id {
+				return fmt.Errorf("item %s lists [%s] as a previous ID, but it's already a previous ID of item %s", id, previousID, otherID)
+			}
+			claimedBy[previousID] = id
+
+			if _, exists := r.lookups.Entries[previousID]; exists {
+				r.entries.Nits = append(r.entries.Nits, fmt.Sprintf("[Item %s] Previous ID %s is still an entry in the database, so it won't lead here until that entry is deleted.", id, previousID))
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkLinkConsistency makes sure related and alternate links go both ways,
+// reporting (or with SymmetriseLinks, fixing) the ones that don't.
+func checkLinkConsistency(entries *EntriesXLSX, lookups ImportLookups, options ReadEntriesOptions) {
+	var ids []string
+	for id := range entries.Entries {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, compareItemIDs)
+
+	// self and doubled links
+	for _, id := range ids {
+		entry := entries.Entries[id]
+		var relatedIDs []string
+		for _, relatedID := range entry.RelatedIDs {
+			if relatedID == id {
+				entries.Nits = append(entries.Nits, fmt.Sprintf("[Item %s] Lists itself as a related item, ignoring it.", id))
+				continue
+			}
+			if !slices.Contains(relatedIDs, relatedID) {
+				relatedIDs = append(relatedIDs, relatedID)
+			}
+		}
+		entry.RelatedIDs = relatedIDs
 		entries.Entries[id] = entry
 	}
 
-	return &entries, nil
+	// one-way links
+	for _, id := range ids {
+		for _, relatedID := range entries.Entries[id].RelatedIDs {
+			other, inSheet := entries.Entries[relatedID]
+			if !inSheet {
+				if !slices.Contains(lookups.Entries[relatedID].RelatedIDs, id) {
+					entries.Nits = append(entries.Nits, fmt.Sprintf("[Item %s] Lists %s as related, but %s is only in the database and doesn't list it back.", id, relatedID, relatedID))
+				}
+				continue
+			}
+			if slices.Contains(other.RelatedIDs, id) {
+				continue
+			}
+			if options.SymmetriseLinks {
+				other.RelatedIDs = append(other.RelatedIDs, id)
+				entries.Entries[relatedID] = other
+				entries.Nits = append(entries.Nits, fmt.Sprintf("[Item %s] Added %s as a related item, since %s lists it.", relatedID, id, id))
+			} else {
+				entries.Nits = append(entries.Nits, fmt.Sprintf("[Item %s] Lists %s as related, but %s doesn't list it back.", id, relatedID, relatedID))
+			}
+		}
+
+		for _, altID := range entries.Entries[id].AltLanguageIDs {
+			other, inSheet := entries.Entries[altID]
+			// alternates that are only in the db are reported while working out languages
+			if altID == id || !inSheet || slices.Contains(other.AltLanguageIDs, id) {
+				continue
+			}
+			if options.SymmetriseLinks {
+				other.AltLanguageIDs = append(other.AltLanguageIDs, id)
+				entries.Entries[altID] = other
+				entries.Nits = append(entries.Nits, fmt.Sprintf("[Item %s] Added %s as an alternate language, since %s lists it.", altID, id, id))
+			} else {
+				entries.Nits = append(entries.Nits, fmt.Sprintf("[Item %s] Lists %s as an alternate language, but %s doesn't list it back.", id, altID, altID))
+			}
+		}
+	}
 }
@@ -0,0 +1,50 @@
+package yps
+
+import "testing"
+
+func TestDetectLanguageMismatch(t *testing.T) {
+	tests := []struct {
+		name         string
+		title        string
+		abstract     string
+		declared     string
+		wantDetected string
+		wantMismatch bool
+	}{
+		{
+			"spanish marked as english",
+			"Juventud, paz y seguridad en América Latina",
+			"Este informe analiza la participación de las personas jóvenes en los procesos de paz de la región.",
+			"en", "es", true,
+		},
+		{
+			"english marked as english",
+			"Youth, peace and security in Latin America",
+			"This report looks at how young people take part in peace processes across the region.",
+			"en", "en", false,
+		},
+		{
+			"english marked as french",
+			"Youth, peace and security in Latin America",
+			"This report looks at how young people take part in peace processes across the region.",
+			"fr", "en", true,
+		},
+		{"short title only", "Paz y juventud", "", "en", "", false},
+		{
+			"undetectable declared language",
+			"Juventud, paz y seguridad en América Latina",
+			"Este informe analiza la participación de las personas jóvenes en los procesos de paz de la región.",
+			"sw", "", false,
+		},
+	}
+
+	for _, test := range tests {
+		detected, confidence, mismatch := detectLanguageMismatch(test.title, test.abstract, test.declared)
+		if detected != test.wantDetected || mismatch != test.wantMismatch {
+			t.Errorf("%s: detected %q with mismatch %v (confidence %.2f), expected %q with mismatch %v", test.name, detected, mismatch, confidence, test.wantDetected, test.wantMismatch)
+		}
+		if mismatch && confidence < languageMismatchConfidence {
+			t.Errorf("%s: mismatch reported below the threshold, confidence %.2f", test.name, confidence)
+		}
+	}
+}